@@ -20,6 +20,13 @@ type Method struct {
 	Description  string   `yaml:"description"`
 }
 
+// TestedBehavior 测试文件中单个测试函数验证的行为
+type TestedBehavior struct {
+	Test     string   `yaml:"test"`
+	Behavior string   `yaml:"behavior"`
+	Cases    []string `yaml:"cases,omitempty"`
+}
+
 type Struct struct {
 	Name    string   `yaml:"name"`
 	Fields  []string `yaml:"fields"`
@@ -29,6 +36,18 @@ type Struct struct {
 type ParsedYAML struct {
 	FunctionDescription string   `yaml:"file_description"`
 	FileInfo            FileInfo `yaml:"file_info"`
+	// Language 非 Go 文件的语言
+	Language string `yaml:"language,omitempty"`
+	// ModulePath 和 ImportPath 根据 go.mod 静态计算
+	ModulePath string `yaml:"module_path,omitempty"`
+	ImportPath string `yaml:"import_path,omitempty"`
+	// StaticEndpoints 静态分析识别到的路由，与 AI 输出的 api_endpoints 互为补充
+	StaticEndpoints []*Endpoint `yaml:"static_endpoints,omitempty"`
+	// TestedBehaviors 和 Fixtures 仅在分析测试文件时输出
+	TestedBehaviors []TestedBehavior `yaml:"tested_behaviors,omitempty"`
+	Fixtures        []string         `yaml:"fixtures,omitempty"`
+	// TestTargets 测试函数 -> 静态分析得到的被测函数
+	TestTargets map[string][]string `yaml:"test_targets,omitempty"`
 	//Constants           []Constant `yaml:"constants"`
 	//Structs             []Struct   `yaml:"structs"`
 	//Methods             []Method   `yaml:"methods"`
@@ -55,29 +55,4 @@ methods:
 	fmt.Printf("  File Name: %s\n", parsedData.FileInfo.FileName)
 	fmt.Printf("  Package Name: %s\n", parsedData.FileInfo.PackageName)
 	fmt.Printf("  Imports: %v\n", parsedData.FileInfo.Imports)
-
-	fmt.Println("Constants:")
-	for _, c := range parsedData.Constants {
-		fmt.Printf("  - Name: %s\n    Value: %s\n    Description: %s\n", c.Name, c.Value, c.Description)
-	}
-
-	fmt.Println("Structs:")
-	for _, s := range parsedData.Structs {
-		fmt.Printf("  - Name: %s\n", s.Name)
-		fmt.Println("    Fields:")
-		for _, f := range s.Fields {
-			fmt.Printf("      - Field Name: %s\n        Field Type: %s\n", f.FieldName, f.FieldType)
-		}
-		fmt.Println("    Methods:")
-		for _, m := range s.Methods {
-			fmt.Printf("      - Method Name: %s\n        Params: %s\n        Return Values: %s\n        Description: %s\n",
-				m.Name, m.Params, m.ReturnValues, m.Description)
-		}
-	}
-
-	fmt.Println("Methods:")
-	for _, m := range parsedData.Methods {
-		fmt.Printf("  - Name: %s\n    Params: %s\n    Return Values: %s\n    Description: %s\n",
-			m.Name, m.Params, m.ReturnValues, m.Description)
-	}
 }
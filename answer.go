@@ -0,0 +1,163 @@
+package code
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TokenUsage 调用模型消耗的 token
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Add 累加另一次调用的用量
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// Sub 两次统计之间的用量
+func (u TokenUsage) Sub(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		PromptTokens:     u.PromptTokens - other.PromptTokens,
+		CompletionTokens: u.CompletionTokens - other.CompletionTokens,
+		TotalTokens:      u.TotalTokens - other.TotalTokens,
+	}
+}
+
+// Citation 回答中引用的源码位置
+type Citation struct {
+	File      string `json:"file"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	// Invalid 引用无效的原因，有效的引用为空
+	Invalid string `json:"invalid,omitempty"`
+	// Text 回答中引用的原文，流式输出的正文没有无效标记，需要按原文指出无效的引用
+	Text string `json:"text,omitempty"`
+}
+
+func (c *Citation) String() string {
+	return fmt.Sprintf("%s:%d-%d", c.File, c.StartLine, c.EndLine)
+}
+
+// Quoted 回答中引用的原文，没有记录原文时使用 String
+func (c *Citation) Quoted() string {
+	if c.Text != "" {
+		return c.Text
+	}
+	return c.String()
+}
+
+// Link markdown 链接，有效的引用链接到源码中的行
+func (c *Citation) Link() string {
+	if c.Invalid != "" {
+		return "`" + c.String() + "` " + invalidCitationMark + " " + c.Invalid
+	}
+	return fmt.Sprintf("[%s](%s#L%d-L%d)", c.String(), c.File, c.StartLine, c.EndLine)
+}
+
+// Answer 问答的结果
+type Answer struct {
+	Question string `json:"question"`
+	// Text 最终的回答
+	Text string `json:"text"`
+	// Files 选择的文件、选择的依据以及每个文件的分析结果
+	Files []*Step1FileInfo `json:"files"`
+	// Diagram 回答中的调用关系图
+	Diagram   string      `json:"diagram,omitempty"`
+	Citations []*Citation `json:"citations,omitempty"`
+	Usage     TokenUsage  `json:"usage"`
+	// Dropped 因超出模型的上下文窗口被缩减或省略的内容
+	Dropped []string `json:"dropped,omitempty"`
+}
+
+// fencedBlockRegex markdown 代码块
+var fencedBlockRegex = regexp.MustCompile("(?s)```[a-zA-Z]*\n(.*?)```")
+
+// extractDiagram 回答中的第一个代码块，提示词要求模型先输出调用关系图
+func extractDiagram(text string) string {
+	if match := fencedBlockRegex.FindStringSubmatch(text); match != nil {
+		return strings.TrimRight(match[1], "\n")
+	}
+	return ""
+}
+
+// PlainText 纯文本格式
+func (a *Answer) PlainText() string {
+	return a.plainTextFiles() + "\n" + strings.TrimSpace(a.Text) + "\n" + a.plainTextFooter()
+}
+
+// PlainTextDetails 不包含回答正文的纯文本格式，回答已经流式输出时使用
+func (a *Answer) PlainTextDetails() string {
+	return "\n" + a.plainTextFiles() + a.plainTextFooter()
+}
+
+// plainTextFiles 选择的文件及依据
+func (a *Answer) plainTextFiles() string {
+	var strBuilder strings.Builder
+	strBuilder.WriteString("相关文件:\n")
+	for _, file := range a.Files {
+		strBuilder.WriteString(fmt.Sprintf("- %s: %s\n", file.File, strings.TrimSpace(file.Why)))
+	}
+	return strBuilder.String()
+}
+
+// plainTextFooter 引用和 token 用量
+func (a *Answer) plainTextFooter() string {
+	var strBuilder strings.Builder
+	if len(a.Citations) > 0 {
+		strBuilder.WriteString("\n引用:\n")
+		for _, citation := range a.Citations {
+			if citation.Invalid != "" {
+				strBuilder.WriteString(fmt.Sprintf("- %s %s 回答中的原文 %q\n", invalidCitationMark, citation.Invalid, citation.Quoted()))
+			} else {
+				strBuilder.WriteString("- " + citation.String() + "\n")
+			}
+		}
+	}
+	if len(a.Dropped) > 0 {
+		strBuilder.WriteString("\n超出上下文长度被缩减的内容:\n")
+		for _, note := range a.Dropped {
+			strBuilder.WriteString("- " + note + "\n")
+		}
+	}
+	strBuilder.WriteString(fmt.Sprintf("\ntokens: prompt %d, completion %d, total %d\n", a.Usage.PromptTokens, a.Usage.CompletionTokens, a.Usage.TotalTokens))
+	return strBuilder.String()
+}
+
+// Markdown markdown 格式，每个文件的分析结果放在折叠块中
+func (a *Answer) Markdown() string {
+	var strBuilder strings.Builder
+	strBuilder.WriteString("# " + a.Question + "\n\n")
+	strBuilder.WriteString(strings.TrimSpace(a.Text))
+	strBuilder.WriteString("\n\n## 相关文件\n\n")
+	for _, file := range a.Files {
+		strBuilder.WriteString(fmt.Sprintf("- `%s`: %s\n", file.File, strings.TrimSpace(file.Why)))
+	}
+	if len(a.Citations) > 0 {
+		strBuilder.WriteString("\n## 引用\n\n")
+		for _, citation := range a.Citations {
+			strBuilder.WriteString("- " + citation.Link() + "\n")
+		}
+	}
+	strBuilder.WriteString("\n## 文件分析\n\n")
+	for _, file := range a.Files {
+		if file.ParseResult == "" {
+			continue
+		}
+		strBuilder.WriteString(fmt.Sprintf("<details>\n<summary>%s</summary>\n\n%s\n\n</details>\n\n", file.File, strings.TrimSpace(file.ParseResult)))
+	}
+	if len(a.Dropped) > 0 {
+		strBuilder.WriteString("## 超出上下文长度被缩减的内容\n\n")
+		for _, note := range a.Dropped {
+			strBuilder.WriteString("- " + note + "\n")
+		}
+		strBuilder.WriteString("\n")
+	}
+	strBuilder.WriteString(fmt.Sprintf("---\ntokens: prompt %d, completion %d, total %d\n", a.Usage.PromptTokens, a.Usage.CompletionTokens, a.Usage.TotalTokens))
+	return strBuilder.String()
+}
@@ -0,0 +1,51 @@
+package code
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAnswer(t *testing.T) {
+	text := "调用关系:\n```\nmain.go\n  |\n  v\nservice.go Login\n```\n登录由 Login 实现 `internal/user/service.go:10-20`。"
+	files := []*Step1FileInfo{
+		{File: "cmd/main.go", Why: "入口", ParseResult: "解析 flag"},
+		{File: "internal/user/service.go", Why: "登录逻辑", ParseResult: "Login 校验密码"},
+	}
+	answer := &Answer{
+		Question:  "登录是怎么实现的",
+		Text:      text,
+		Files:     files,
+		Diagram:   extractDiagram(text),
+		Citations: []*Citation{{File: "internal/user/service.go", StartLine: 10, EndLine: 20}, {File: "cmd/main.go", StartLine: 90, EndLine: 99, Invalid: "out of range", Text: "main.go:90-99"}},
+		Usage:     TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+	if answer.Diagram != "main.go\n  |\n  v\nservice.go Login" {
+		t.Errorf("diagram = %q", answer.Diagram)
+	}
+
+	markdown := answer.Markdown()
+	for _, want := range []string{"# 登录是怎么实现的", "- `cmd/main.go`: 入口", "<summary>internal/user/service.go</summary>", "total 15",
+		"[internal/user/service.go:10-20](internal/user/service.go#L10-L20)", "`cmd/main.go:90-99` [无效引用] out of range"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown missing %q:\n%s", want, markdown)
+		}
+	}
+
+	// 流式输出的正文中没有无效标记，末尾按原文列出无效的引用
+	if details := answer.PlainTextDetails(); !strings.Contains(details, `[无效引用] out of range 回答中的原文 "main.go:90-99"`) {
+		t.Errorf("details missing invalid citation:\n%s", details)
+	}
+
+	data, err := json.Marshal(answer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if files := decoded["files"].([]interface{}); files[1].(map[string]interface{})["analysis"] != "Login 校验密码" {
+		t.Errorf("unexpected json: %s", data)
+	}
+}
@@ -0,0 +1,262 @@
+package code
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"gopkg.in/yaml.v3"
+)
+
+// maxHistoryTurns 对话时发送给 AI 的最近几轮问答
+const maxHistoryTurns = 10
+
+// ChatTurn 一轮问答
+type ChatTurn struct {
+	Question  string      `yaml:"question"`
+	Answer    string      `yaml:"answer"`
+	Files     []string    `yaml:"files,omitempty"`
+	Citations []*Citation `yaml:"citations,omitempty"`
+	Usage     TokenUsage  `yaml:"usage"`
+}
+
+// ChatSession 多轮对话的会话：历史问答、已经分析过的文件和用户打开的文件，保存到文件后可以恢复
+type ChatSession struct {
+	ID        string      `yaml:"id"`
+	Root      string      `yaml:"root"`
+	CreatedAt time.Time   `yaml:"created_at"`
+	UpdatedAt time.Time   `yaml:"updated_at"`
+	Turns     []*ChatTurn `yaml:"turns"`
+	// Files 已经检索并分析过的文件，后续的问题只分析新增的文件
+	Files map[string]*Step1FileInfo `yaml:"files"`
+	// Opened 通过 /open 打开的文件，全文附加到之后的问题中
+	Opened []string `yaml:"opened,omitempty"`
+
+	path string
+}
+
+// NewChatSession 创建新的会话，会话保存在 dir 目录下
+func NewChatSession(dir, root string) *ChatSession {
+	now := time.Now()
+	id := now.Format("20060102-150405")
+	return &ChatSession{
+		ID:        id,
+		Root:      root,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Files:     make(map[string]*Step1FileInfo),
+		path:      filepath.Join(dir, id+".yaml"),
+	}
+}
+
+// LoadChatSession 读取保存的会话
+func LoadChatSession(path string) (*ChatSession, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	session := &ChatSession{}
+	if err := yaml.Unmarshal(data, session); err != nil {
+		return nil, err
+	}
+	if session.Files == nil {
+		session.Files = make(map[string]*Step1FileInfo)
+	}
+	session.path = path
+	return session, nil
+}
+
+// Path 会话的保存路径
+func (s *ChatSession) Path() string {
+	return s.path
+}
+
+// Save 保存会话，path 不为空时另存为该路径并在之后使用新路径
+func (s *ChatSession) Save(path string) error {
+	if path != "" {
+		s.path = path
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// Reset 清空历史问答和文件
+func (s *ChatSession) Reset() {
+	s.Turns = nil
+	s.Files = make(map[string]*Step1FileInfo)
+	s.Opened = nil
+}
+
+// Open 打开文件，之后的问题会附带文件全文
+func (s *ChatSession) Open(file string) {
+	if !containsString(s.Opened, file) {
+		s.Opened = append(s.Opened, file)
+	}
+}
+
+// FileNames 已经分析过的文件，按路径排序
+func (s *ChatSession) FileNames() []string {
+	names := make([]string, 0, len(s.Files))
+	for name := range s.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AIChat 在会话中回答问题：只分析新检索到的文件，历史问答、已分析的文件和打开的文件都会作为上下文
+func (c *ChatGPTClient) AIChat(session *ChatSession, summaryContent, question, helpInfo string) (*Answer, error) {
+	startUsage := c.usage
+	c.dropped = nil
+	answer := &Answer{Question: question}
+
+	// 检索新的文件，已经分析过的文件直接复用
+	step1Prompt := c.fitSummary(summaryContent, func(summary string) string {
+		return buildChatRelFilesPrompt(question, session.history(), session.FileNames(), summary)
+	})
+	step1Response, infos, err := c.selectFiles(step1Prompt)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if old, ok := session.Files[info.File]; ok {
+			answer.Files = append(answer.Files, old)
+			continue
+		}
+		if c.analyzeFile(question, step1Response, info) {
+			session.Files[info.File] = info
+			answer.Files = append(answer.Files, info)
+		}
+	}
+
+	messages := c.chatMessages(session, answer.Files, question, helpInfo)
+	response, err := c.getChatGPTStreamResponse(messages)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*Step1FileInfo, 0, len(session.Files))
+	for _, name := range session.FileNames() {
+		files = append(files, session.Files[name])
+	}
+	sent := sentLines(files)
+	for name, ranges := range openedLines(messages[0].Content, session.Opened) {
+		sent[name] = append(sent[name], ranges...)
+	}
+	c.finishAnswer(answer, response, startUsage, sent)
+
+	turn := &ChatTurn{Question: question, Answer: answer.Text, Citations: answer.Citations, Usage: answer.Usage}
+	for _, file := range answer.Files {
+		turn.Files = append(turn.Files, file.File)
+	}
+	session.Turns = append(session.Turns, turn)
+	session.UpdatedAt = time.Now()
+	return answer, nil
+}
+
+// chatMessages 对话的消息：系统消息、最近几轮问答和当前问题
+//
+// 历史问答最多使用一半的预算，从最早的一轮开始省略；系统消息中的文件按本轮相关的文件、打开的文件、之前分析的文件的顺序放入剩余的预算
+func (c *ChatGPTClient) chatMessages(session *ChatSession, files []*Step1FileInfo, question, helpInfo string) []openai.ChatCompletionMessage {
+	system := buildChatSystemPrompt(helpInfo).String()
+	turns := session.Turns
+	if len(turns) > maxHistoryTurns {
+		turns = turns[len(turns)-maxHistoryTurns:]
+	}
+	historyBudget := (c.budget.Limit - EstimateTokens(system+question)) / 2
+	history := ""
+	start := len(turns)
+	for ; start > 0; start-- {
+		turn := turns[start-1]
+		if EstimateTokens(history+turn.Question+turn.Answer) > historyBudget {
+			break
+		}
+		history += turn.Question + turn.Answer
+	}
+	if start > 0 {
+		c.drop([]string{fmt.Sprintf("较早的 %d 轮对话: 已省略", start)})
+	}
+	turns = turns[start:]
+
+	kept, dropped := c.budget.Fit(system+history+question, c.chatSections(session, files))
+	c.drop(dropped)
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleSystem, Content: system + strings.Join(kept, "")}}
+	for _, turn := range turns {
+		messages = append(messages,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: turn.Question},
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: turn.Answer})
+	}
+	return append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: question})
+}
+
+// chatSections 系统消息中的文件：本轮相关文件的分析结果、打开的文件源码、之前分析过的其他文件的分析结果
+func (c *ChatGPTClient) chatSections(session *ChatSession, files []*Step1FileInfo) []BudgetSection {
+	var sections []BudgetSection
+	current := make(map[string]bool)
+	analysis := func(info *Step1FileInfo) BudgetSection {
+		return BudgetSection{
+			Name:    info.File + " 的分析结果",
+			Text:    fmt.Sprintf("\n### %s 的分析结果\n%s\n", info.File, info.ParseResult),
+			Summary: fmt.Sprintf("\n### %s\n选择该文件的依据: %s\n", info.File, strings.TrimSpace(info.Why)),
+		}
+	}
+	for _, info := range files {
+		current[info.File] = true
+		sections = append(sections, analysis(info))
+	}
+	for _, name := range session.Opened {
+		content, err := os.ReadFile(c.sourcePath(name))
+		if err != nil {
+			continue
+		}
+		sections = append(sections, BudgetSection{
+			Name: name + " 的源码",
+			Text: fmt.Sprintf("\n### %s 源码\n%s", name, NumberLines(string(content), 1)),
+		})
+	}
+	for _, name := range session.FileNames() {
+		if !current[name] {
+			sections = append(sections, analysis(session.Files[name]))
+		}
+	}
+	return sections
+}
+
+// openedLines 系统消息中各个打开的文件实际发送的源码行，超出预算被省略或截断的部分不包含在内
+func openedLines(system string, opened []string) map[string][]LineRange {
+	sent := make(map[string][]LineRange)
+	for _, name := range opened {
+		start := strings.Index(system, "\n### "+name+" 源码\n")
+		if start < 0 {
+			continue
+		}
+		section := system[start+1:]
+		if end := strings.Index(section, "\n### "); end >= 0 {
+			section = section[:end]
+		}
+		sent[name] = NumberedLineRanges(section)
+	}
+	return sent
+}
+
+// history 最近几轮的问题，用于选择文件
+func (s *ChatSession) history() string {
+	turns := s.Turns
+	if len(turns) > maxHistoryTurns {
+		turns = turns[len(turns)-maxHistoryTurns:]
+	}
+	var strBuilder strings.Builder
+	for _, turn := range turns {
+		strBuilder.WriteString("- " + turn.Question + "\n")
+	}
+	return strBuilder.String()
+}
@@ -0,0 +1,52 @@
+package code
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChatSession(t *testing.T) {
+	dir := t.TempDir()
+	session := NewChatSession(dir, "/src/project")
+	session.Files["b.go"] = &Step1FileInfo{File: "b.go", Why: "重试逻辑", ParseResult: "b 的分析"}
+	session.Files["a.go"] = &Step1FileInfo{File: "a.go", Why: "入口"}
+	session.Open("a.go")
+	session.Open("a.go")
+	for i := 0; i < maxHistoryTurns+2; i++ {
+		session.Turns = append(session.Turns, &ChatTurn{Question: strings.Repeat("问", i+1), Answer: "答"})
+	}
+	if err := session.Save(""); err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, session.ID+".yaml"); session.Path() != want {
+		t.Errorf("path = %s, want %s", session.Path(), want)
+	}
+
+	loaded, err := LoadChatSession(session.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ID != session.ID || loaded.Root != "/src/project" || len(loaded.Turns) != maxHistoryTurns+2 {
+		t.Errorf("loaded = %+v", loaded)
+	}
+	if want := []string{"a.go", "b.go"}; !reflect.DeepEqual(loaded.FileNames(), want) {
+		t.Errorf("files = %v, want %v", loaded.FileNames(), want)
+	}
+	if loaded.Files["b.go"].ParseResult != "b 的分析" {
+		t.Errorf("analysis of b.go = %q", loaded.Files["b.go"].ParseResult)
+	}
+	if want := []string{"a.go"}; !reflect.DeepEqual(loaded.Opened, want) {
+		t.Errorf("opened = %v, want %v", loaded.Opened, want)
+	}
+	// 只保留最近的几轮问题
+	if history := loaded.history(); strings.Count(history, "\n") != maxHistoryTurns || strings.Contains(history, "- 问\n") {
+		t.Errorf("history = %q", history)
+	}
+
+	loaded.Reset()
+	if len(loaded.Turns) != 0 || len(loaded.Files) != 0 || len(loaded.Opened) != 0 {
+		t.Errorf("reset session = %+v", loaded)
+	}
+}
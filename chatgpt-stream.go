@@ -0,0 +1,98 @@
+package code
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// SetStreamWriter 设置最终回答的流式输出，模型生成的内容会边生成边写入 w，为 nil 时不使用流式请求
+func (c *ChatGPTClient) SetStreamWriter(w io.Writer) {
+	c.stream = w
+}
+
+// chatStream 流式回复，openai.ChatCompletionStream 实现了该接口
+type chatStream interface {
+	Recv() (openai.ChatCompletionStreamResponse, error)
+}
+
+// getChatGPTStreamResponse 使用流式请求获取回复，内容同时写入流式输出，返回完整的回复
+//
+// 没有设置流式输出、服务不支持流式请求或者在输出任何内容之前失败时，回退为普通请求
+func (c *ChatGPTClient) getChatGPTStreamResponse(messages []openai.ChatCompletionMessage) (string, error) {
+	if c.stream == nil {
+		return c.getChatGPTMessagesResponse(messages)
+	}
+	req := openai.ChatCompletionRequest{
+		Temperature:   0,
+		Model:         ChatGPTModel,
+		Messages:      messages,
+		Stream:        true,
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	}
+	stream, err := c.client.CreateChatCompletionStream(context.Background(), req)
+	if err != nil {
+		log.Printf("Streaming request failed, falling back to a normal request: %v\n", err)
+		return c.getChatGPTFallbackResponse(messages)
+	}
+	defer stream.Close()
+
+	response, usage, written, err := readChatStream(stream, c.stream)
+	c.usage.Add(usage)
+	if err != nil {
+		if !written {
+			log.Printf("Streaming response failed, falling back to a normal request: %v\n", err)
+			return c.getChatGPTFallbackResponse(messages)
+		}
+		return "", fmt.Errorf("ChatGPT stream failed: %v", err)
+	}
+	return response, nil
+}
+
+// getChatGPTFallbackResponse 流式请求失败时使用普通请求，完整的回复一次写入流式输出
+func (c *ChatGPTClient) getChatGPTFallbackResponse(messages []openai.ChatCompletionMessage) (string, error) {
+	response, err := c.getChatGPTMessagesResponse(messages)
+	if err != nil {
+		return "", err
+	}
+	io.WriteString(c.stream, response+"\n")
+	return response, nil
+}
+
+// readChatStream 读取流式回复直到结束，每段内容写入 w，返回完整的回复、token 用量以及是否已经输出过内容
+func readChatStream(stream chatStream, w io.Writer) (string, TokenUsage, bool, error) {
+	var strBuilder strings.Builder
+	var usage TokenUsage
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return strBuilder.String(), usage, strBuilder.Len() > 0, err
+		}
+		// 开启 include_usage 时最后一段只包含用量
+		if chunk.Usage != nil {
+			usage = TokenUsage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		content := chunk.Choices[0].Delta.Content
+		strBuilder.WriteString(content)
+		if _, err := io.WriteString(w, content); err != nil {
+			return strBuilder.String(), usage, true, err
+		}
+	}
+	io.WriteString(w, "\n")
+	return strBuilder.String(), usage, strBuilder.Len() > 0, nil
+}
@@ -0,0 +1,65 @@
+package code
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// fakeChatStream 依次返回预设的分段，之后返回 err(默认为 io.EOF)
+type fakeChatStream struct {
+	chunks []openai.ChatCompletionStreamResponse
+	err    error
+}
+
+func (s *fakeChatStream) Recv() (openai.ChatCompletionStreamResponse, error) {
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return openai.ChatCompletionStreamResponse{}, s.err
+		}
+		return openai.ChatCompletionStreamResponse{}, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func contentChunk(content string) openai.ChatCompletionStreamResponse {
+	return openai.ChatCompletionStreamResponse{Choices: []openai.ChatCompletionStreamChoice{{Delta: openai.ChatCompletionStreamChoiceDelta{Content: content}}}}
+}
+
+func TestReadChatStream(t *testing.T) {
+	stream := &fakeChatStream{chunks: []openai.ChatCompletionStreamResponse{
+		contentChunk("登录由 "),
+		contentChunk(""),
+		contentChunk("auth.go:10-20 实现"),
+		{Usage: &openai.Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}},
+	}}
+	var out strings.Builder
+	response, usage, written, err := readChatStream(stream, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != "登录由 auth.go:10-20 实现" || !written {
+		t.Errorf("response = %q, written = %v", response, written)
+	}
+	if out.String() != response+"\n" {
+		t.Errorf("output = %q", out.String())
+	}
+	if usage.TotalTokens != 120 || usage.PromptTokens != 100 {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	// 输出内容之前失败时可以回退为普通请求
+	_, _, written, err = readChatStream(&fakeChatStream{err: errors.New("unsupported")}, io.Discard)
+	if err == nil || written {
+		t.Errorf("err = %v, written = %v", err, written)
+	}
+	_, _, written, err = readChatStream(&fakeChatStream{chunks: []openai.ChatCompletionStreamResponse{contentChunk("部分")}, err: errors.New("reset")}, io.Discard)
+	if err == nil || !written {
+		t.Errorf("err = %v, written = %v", err, written)
+	}
+}
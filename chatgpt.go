@@ -5,21 +5,49 @@ import (
 	"fmt"
 	"github.com/sashabaranov/go-openai"
 	"gopkg.in/yaml.v3"
+	"io"
+	"log"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 )
 
 // Step1FileInfo 结构体表示文件信息
 type Step1FileInfo struct {
-	File        string
-	Why         string
-	ParseResult string
+	File string `json:"file" yaml:"file"`
+	Why  string `json:"why" yaml:"why"`
+	// Symbols 与问题相关的函数或类型，为空时发送整个文件
+	Symbols []string `json:"symbols,omitempty" yaml:"symbols,omitempty"`
+	// ParseResult 第二步对该文件的分析结果
+	ParseResult string `json:"analysis,omitempty" yaml:"analysis,omitempty"`
+	// Lines 第二步发送给模型的源码行，回答只能引用这些行
+	Lines []LineRange `json:"lines,omitempty" yaml:"lines,omitempty"`
 }
 
+// ChatGPTModel 使用的模型
+const ChatGPTModel = openai.GPT4oMini
+
 // ChatGPTClient 结构体封装 ChatGPT 客户端
 type ChatGPTClient struct {
 	client *openai.Client
+	// factsParser 不为空时，问答流程会附带文件中函数的静态信息
+	factsParser *Parser
+	// sourceRoot 总结文件中相对路径的根目录
+	sourceRoot string
+	// symbolHints 检索得到的文件 -> 相关符号，AI 没有选择符号时使用
+	symbolHints map[string][]string
+	// knownFiles 结果索引中的源文件，问答时只允许读取这些文件
+	knownFiles []string
+	// usage 累计消耗的 token
+	usage TokenUsage
+	// stream 最终回答的流式输出
+	stream io.Writer
+	// budget 每次请求的 token 预算
+	budget *TokenBudget
+	// dropped 本次问答中因超出预算被缩减的内容
+	dropped []string
 }
 
 // NewChatGPTClient 创建新的 ChatGPTClient
@@ -28,23 +56,57 @@ func NewChatGPTClient(apiKey string) *ChatGPTClient {
 	cfg.BaseURL = "https://api.chatanywhere.tech/v1"
 	return &ChatGPTClient{
 		client: openai.NewClientWithConfig(cfg),
+		budget: NewTokenBudget(ChatGPTModel),
+	}
+}
+
+// EnableInternalFacts 问答时解析相关文件(包括未导出的函数)，把调用关系、字段读写和返回的错误附加到提示词中
+func (c *ChatGPTClient) EnableInternalFacts() {
+	c.factsParser = NewParserWithOptions(ParserOptions{IncludeUnexported: true})
+}
+
+// SetSourceRoot 设置总结文件中相对路径的根目录，问答时据此读取源文件
+func (c *ChatGPTClient) SetSourceRoot(root string) {
+	c.sourceRoot = root
+}
+
+// SetKnownFiles 设置结果索引中的源文件，问答时 AI 选择的文件必须是其中之一(允许修正相近的路径)
+func (c *ChatGPTClient) SetKnownFiles(files []string) {
+	c.knownFiles = files
+}
+
+// SetSymbolHints 设置检索得到的每个文件中的相关符号，AI 选择文件时没有给出符号则使用这些符号
+func (c *ChatGPTClient) SetSymbolHints(hints map[string][]string) {
+	c.symbolHints = hints
+}
+
+// sourcePath 把总结文件中的路径转换为可读取的路径
+func (c *ChatGPTClient) sourcePath(path string) string {
+	if c.sourceRoot == "" || filepath.IsAbs(path) {
+		return path
 	}
+	return filepath.Join(c.sourceRoot, filepath.FromSlash(path))
 }
 
 // getChatGPTResponse 调用 ChatGPT API 并返回回复
 func (c *ChatGPTClient) getChatGPTResponse(prompt string) (string, error) {
+	return c.getChatGPTMessagesResponse([]openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: prompt,
+		},
+	})
+}
+
+// getChatGPTMessagesResponse 使用多轮消息调用 ChatGPT API 并返回回复
+func (c *ChatGPTClient) getChatGPTMessagesResponse(messages []openai.ChatCompletionMessage) (string, error) {
 	ctx := context.Background()
 	// 构造请求消息
 	req := openai.ChatCompletionRequest{
 		Temperature: 0,
-		Model:       openai.GPT4oMini, // 使 用 GPT-3.5 Turbo 模型
+		Model:       ChatGPTModel,
 		//Model: openai.CodexCodeDavinci002, // 使用 GPT-3.5 Turbo 模型
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
+		Messages: messages,
 	}
 
 	// 调用 OpenAI API 获取回复
@@ -53,15 +115,73 @@ func (c *ChatGPTClient) getChatGPTResponse(prompt string) (string, error) {
 		return "", fmt.Errorf("ChatGPT request failed: %v", err)
 	}
 
+	c.usage.Add(TokenUsage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		TotalTokens:      resp.Usage.TotalTokens,
+	})
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("ChatGPT returned no choices")
+	}
 	// 返回模型的回复内容
 	return resp.Choices[0].Message.Content, nil
 }
 
+// Usage 客户端累计消耗的 token
+func (c *ChatGPTClient) Usage() TokenUsage {
+	return c.usage
+}
+
+// ChatGPTEmbeddingModel 生成向量使用的模型
+const ChatGPTEmbeddingModel = openai.SmallEmbedding3
+
+// Embed 实现 EmbeddingProvider，返回的向量与 texts 一一对应
+func (c *ChatGPTClient) Embed(texts []string) ([][]float32, error) {
+	resp, err := c.client.CreateEmbeddings(context.Background(), openai.EmbeddingRequest{
+		Input: texts,
+		Model: ChatGPTEmbeddingModel,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %v", err)
+	}
+	vectors := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+	return vectors, nil
+}
+
 func (c *ChatGPTClient) AIAnalysisCode(filename, code string) (string, ParsedYAML, error) {
 	response, err := c.getChatGPTResponse(buildFileAnalysisPrompt(filename, code))
 	if err != nil {
 		return "", ParsedYAML{}, err
 	}
+	return parseAnalysisResponse(response)
+}
+
+// AIAnalysisLanguage 使用语言插件的提示词分析文件
+func (c *ChatGPTClient) AIAnalysisLanguage(lang Language, filename, code string) (string, ParsedYAML, error) {
+	response, err := c.getChatGPTResponse(lang.AnalysisPrompt(filename, code))
+	if err != nil {
+		return "", ParsedYAML{}, err
+	}
+	return parseAnalysisResponse(response)
+}
+
+// AIAnalysisTestCode 使用测试专用的提示词分析测试文件：测试的行为、表驱动用例和测试夹具
+func (c *ChatGPTClient) AIAnalysisTestCode(filename, code string) (string, ParsedYAML, error) {
+	response, err := c.getChatGPTResponse(buildTestFileAnalysisPrompt(filename, code))
+	if err != nil {
+		return "", ParsedYAML{}, err
+	}
+	return parseAnalysisResponse(response)
+}
+
+// parseAnalysisResponse 清理模型输出并解析为 ParsedYAML，解析失败时仍返回原始内容
+func parseAnalysisResponse(response string) (string, ParsedYAML, error) {
 	response = strings.TrimSpace(response)
 	response = strings.TrimLeft(response, "```yaml")
 	response = strings.TrimLeft(response, "\n")
@@ -85,7 +205,7 @@ func (c *ChatGPTClient) AIAnalysisCode(filename, code string) (string, ParsedYAM
 	}
 
 	var parsedData ParsedYAML
-	err = yaml.Unmarshal([]byte(response), &parsedData)
+	err := yaml.Unmarshal([]byte(response), &parsedData)
 	if err != nil {
 		fmt.Println("Error parsing YAML:", err)
 		return response, parsedData, nil
@@ -94,12 +214,53 @@ func (c *ChatGPTClient) AIAnalysisCode(filename, code string) (string, ParsedYAM
 	return response, parsedData, nil
 }
 
-func (c *ChatGPTClient) AIQuestion(summaryContent, question, helpInfo string) ([]string, error) {
+// AIQuestion 分三步回答问题：根据总结信息选择相关文件、逐个分析文件、汇总分析结果生成回答
+//
+// 过程信息输出到日志，结果以 Answer 返回
+func (c *ChatGPTClient) AIQuestion(summaryContent, question, helpInfo string) (*Answer, error) {
+	startUsage := c.usage
+	c.dropped = nil
+	answer := &Answer{Question: question}
+
+	step1Prompt := c.fitSummary(summaryContent, func(summary string) string {
+		return buildQuestionRelFilesPrompt(question, summary)
+	})
+	step1Response, step1FileInfos, err := c.selectFiles(step1Prompt)
+	if err != nil {
+		return nil, err
+	}
+	if len(step1FileInfos) == 0 {
+		return nil, fmt.Errorf("no valid files selected for the question")
+	}
+
+	for _, step1FileInfo := range step1FileInfos {
+		if c.analyzeFile(question, step1Response, step1FileInfo) {
+			answer.Files = append(answer.Files, step1FileInfo)
+		}
+	}
+	if len(answer.Files) == 0 {
+		return nil, fmt.Errorf("none of the selected files could be read")
+	}
+
+	answerPromptBuilder := buildFinalAnswerPrompt(question, helpInfo)
+	for _, analysis := range c.fitAnalyses(answerPromptBuilder.String(), answer.Files) {
+		answerPromptBuilder.WriteString(analysis)
+	}
 
-	step1Response, err := c.getChatGPTResponse(buildQuestionRelFilesPrompt(question, summaryContent))
+	response, err := c.getChatGPTStreamResponse([]openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: answerPromptBuilder.String()}})
 	if err != nil {
 		return nil, err
 	}
+	c.finishAnswer(answer, response, startUsage, sentLines(answer.Files))
+	return answer, nil
+}
+
+// selectFiles 第一步：让 AI 选择相关的文件，返回 AI 的原始输出和解析、校验后的文件
+func (c *ChatGPTClient) selectFiles(prompt string) (string, []*Step1FileInfo, error) {
+	step1Response, err := c.getChatGPTResponse(prompt)
+	if err != nil {
+		return "", nil, err
+	}
 
 	step1Response = strings.TrimSpace(step1Response)
 	step1Response = strings.TrimLeft(step1Response, "```yaml")
@@ -108,44 +269,252 @@ func (c *ChatGPTClient) AIQuestion(summaryContent, question, helpInfo string) ([
 	var step1FileInfos []*Step1FileInfo
 	err = yaml.Unmarshal([]byte(step1Response), &step1FileInfos)
 	if err != nil {
-		fmt.Println("Step1FileInfo Error parsing YAML2:", err)
-		fmt.Println(step1Response)
-		return nil, err
+		log.Printf("Step1FileInfo Error parsing YAML: %v\n%s\n", err, step1Response)
+		return "", nil, err
 	}
 
-	fmt.Println("----------需要召回的文件列表-------------")
+	step1FileInfos = c.resolveFiles(step1FileInfos)
 	for _, step1FileInfo := range step1FileInfos {
-		fmt.Println(step1FileInfo.File)
-		fmt.Println(step1FileInfo.Why)
+		log.Printf("选择文件 %s: %s\n", step1FileInfo.File, step1FileInfo.Why)
 	}
+	return step1Response, step1FileInfos, nil
+}
 
-	for _, step1FileInfo := range step1FileInfos {
-		fileContent, err := os.ReadFile(step1FileInfo.File)
-		if err != nil {
-			return nil, err
+// analyzeFile 第二步：结合问题分析单个文件，结果保存在 info.ParseResult 中，文件无法读取或分析失败时返回 false
+func (c *ChatGPTClient) analyzeFile(question, step1Response string, info *Step1FileInfo) bool {
+	fileContent, err := c.fileContext(info)
+	if err != nil {
+		log.Printf("警告: 跳过无法读取的文件 %s: %v\n", info.File, err)
+		return false
+	}
+
+	facts := ""
+	if c.factsParser != nil {
+		if parseResult, err := c.factsParser.ParseByFile(c.sourcePath(info.File)); err == nil {
+			facts = parseResult.FormatFuncFacts()
+		}
+	}
+
+	fileContent, dropped := c.budget.FitText(buildQuestionRelFilesParsePrompt(question, step1Response, info.File, "", facts), info.File+" 的源码", fileContent)
+	c.drop(dropped)
+	info.Lines = NumberedLineRanges(fileContent)
+
+	log.Printf("分析文件 %s\n", info.File)
+	response, err := c.getChatGPTResponse(buildQuestionRelFilesParsePrompt(question, step1Response, info.File, fileContent, facts))
+	if err != nil {
+		log.Printf("警告: 分析文件 %s 失败: %v\n", info.File, err)
+		return false
+	}
+	info.ParseResult = response
+	return true
+}
+
+// finishAnswer 第三步完成后：提取调用关系图、验证引用并统计本次问答的 token 用量，sent 为发送给模型的源码行
+func (c *ChatGPTClient) finishAnswer(answer *Answer, response string, startUsage TokenUsage, sent map[string][]LineRange) {
+	answer.Diagram = extractDiagram(response)
+	answer.Text, answer.Citations = c.verifyCitations(strings.TrimSpace(response), sent)
+	answer.Usage = c.usage.Sub(startUsage)
+	answer.Dropped = c.dropped
+}
+
+// sentLines 各个文件在第二步发送给模型的源码行
+func sentLines(files []*Step1FileInfo) map[string][]LineRange {
+	sent := make(map[string][]LineRange, len(files))
+	for _, file := range files {
+		sent[file.File] = append(sent[file.File], file.Lines...)
+	}
+	return sent
+}
+
+// fitSummary 把总结信息放入预算，排在后面的文件条目先缩减为文件名和功能，仍然超出时省略
+func (c *ChatGPTClient) fitSummary(summary string, buildPrompt func(summary string) string) string {
+	kept, dropped := c.budget.Fit(buildPrompt(""), SummarySections(summary))
+	c.drop(dropped)
+	return buildPrompt(strings.Join(kept, ""))
+}
+
+// fitAnalyses 把各个文件的分析结果放入最终回答的预算，排在后面的文件先只保留选择依据，仍然超出时截断或省略
+func (c *ChatGPTClient) fitAnalyses(prompt string, files []*Step1FileInfo) []string {
+	sections := make([]BudgetSection, 0, len(files))
+	for _, file := range files {
+		sections = append(sections, BudgetSection{
+			Name:    file.File + " 的分析结果",
+			Text:    file.ParseResult,
+			Summary: fmt.Sprintf("\n%s 的分析结果过长已省略，选择该文件的依据: %s\n", file.File, strings.TrimSpace(file.Why)),
+		})
+	}
+	kept, dropped := c.budget.Fit(prompt, sections)
+	c.drop(dropped)
+	return kept
+}
+
+// drop 记录因超出预算被缩减的内容
+func (c *ChatGPTClient) drop(dropped []string) {
+	for _, note := range dropped {
+		log.Printf("超出上下文预算, %s\n", note)
+	}
+	c.dropped = append(c.dropped, dropped...)
+}
+
+// AISummarizePackage 根据包内各文件的摘要生成包摘要
+func (c *ChatGPTClient) AISummarizePackage(pkg, fileSummaries string) (string, error) {
+	return c.getChatGPTResponse(buildPackageSummaryPrompt(pkg, fileSummaries))
+}
+
+// AISummarizeArchitecture 根据包摘要生成仓库的架构概览
+func (c *ChatGPTClient) AISummarizeArchitecture(packages string) (string, error) {
+	return c.getChatGPTResponse(buildArchitecturePrompt(packages))
+}
+
+// AISelectPackages 根据架构概览和包摘要选择与问题相关的包，模型返回的未知包会被忽略
+func (c *ChatGPTClient) AISelectPackages(tree *SummaryTree, question string) ([]string, error) {
+	kept, dropped := c.budget.Fit(buildQuestionRelPackagesPrompt(question, tree.Overview, ""), tree.PackageSections())
+	c.drop(dropped)
+	response, err := c.getChatGPTResponse(buildQuestionRelPackagesPrompt(question, tree.Overview, strings.Join(kept, "")))
+	if err != nil {
+		return nil, err
+	}
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```yaml")
+	response = strings.TrimSuffix(response, "```")
+
+	var selected []struct {
+		Package string `yaml:"package"`
+		Why     string `yaml:"why"`
+	}
+	if err := yaml.Unmarshal([]byte(response), &selected); err != nil {
+		return nil, fmt.Errorf("failed to parse selected packages: %v", err)
+	}
+
+	var packages []string
+	for _, s := range selected {
+		if tree.Package(s.Package) == nil {
+			log.Printf("忽略未知的包: %s\n", s.Package)
+			continue
 		}
+		log.Printf("选择包 %s: %s\n", s.Package, s.Why)
+		packages = append(packages, s.Package)
+	}
+	return packages, nil
+}
 
-		response, err := c.getChatGPTResponse(buildQuestionRelFilesParsePrompt(question, step1Response, step1FileInfo.File, string(fileContent)))
+// resolveFiles 把 AI 选择的文件解析为分析目录中已知的文件，修正相近的路径，跳过未知的文件和重复的文件
+func (c *ChatGPTClient) resolveFiles(infos []*Step1FileInfo) []*Step1FileInfo {
+	resolver := NewFileResolver(c.sourceRoot, c.knownFiles)
+	seen := make(map[string]bool)
+	var resolved []*Step1FileInfo
+	for _, info := range infos {
+		file, err := resolver.Resolve(info.File)
 		if err != nil {
-			return nil, err
+			log.Printf("警告: 忽略 AI 选择的文件 %s: %v\n", info.File, err)
+			continue
+		}
+		if file != info.File {
+			log.Printf("修正文件路径 %s -> %s\n", info.File, file)
+			info.File = file
+		}
+		if seen[file] {
+			continue
 		}
-		fmt.Println("-----", step1FileInfo.File, "分析结果")
-		fmt.Println(response)
-		step1FileInfo.ParseResult = response
+		seen[file] = true
+		resolved = append(resolved, info)
 	}
+	return resolved
+}
 
-	answerPromptBuilder := buildFinalAnswerPrompt(question, helpInfo)
-	for _, step1FileInfo := range step1FileInfos {
-		answerPromptBuilder.WriteString(string(step1FileInfo.ParseResult))
+// verifyCitations 验证回答中的引用，sent 为发送给模型的源码行，无效的引用会被标记
+func (c *ChatGPTClient) verifyCitations(text string, sent map[string][]LineRange) (string, []*Citation) {
+	resolver := NewFileResolver(c.sourceRoot, c.knownFiles)
+	lineCounts := make(map[string]int)
+	verified, citations := VerifyCitations(text, resolver, func(file string) (int, error) {
+		if n, ok := lineCounts[file]; ok {
+			return n, nil
+		}
+		content, err := os.ReadFile(c.sourcePath(file))
+		if err != nil {
+			return 0, err
+		}
+		n := strings.Count(strings.TrimRight(string(content), "\n"), "\n") + 1
+		lineCounts[file] = n
+		return n, nil
+	}, sent)
+	for _, citation := range citations {
+		if citation.Invalid != "" {
+			log.Printf("无效引用 %s: %s\n", citation.String(), citation.Invalid)
+		}
 	}
+	return verified, citations
+}
 
-	response, err := c.getChatGPTResponse(answerPromptBuilder.String())
+// fileContext 问答时发送的带行号的文件内容：小文件和非 Go 文件发送全文，否则只发送相关符号的源码、签名和调用关系
+func (c *ChatGPTClient) fileContext(info *Step1FileInfo) (string, error) {
+	path := c.sourcePath(info.File)
+	content, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	symbols := info.Symbols
+	if len(symbols) == 0 {
+		symbols = c.symbolHints[info.File]
+	}
+	if len(symbols) == 0 || filepath.Ext(path) != ".go" || strings.Count(string(content), "\n") < SmallFileLines {
+		return NumberLines(string(content), 1), nil
 	}
 
-	fmt.Println(response)
-	return nil, nil
+	ctx, err := ExtractSymbolContext(path, symbols)
+	if err != nil || len(ctx.Snippets) == 0 {
+		return NumberLines(string(content), 1), nil
+	}
+	log.Printf("%s: 只发送符号 %v\n", info.File, symbols)
+	return ctx.Format(), nil
+}
+
+// AIDescribeOperations 为 OpenAPI 文档中的每个接口生成摘要和描述，results 用于提供处理函数的静态信息
+func (c *ChatGPTClient) AIDescribeOperations(doc *OpenAPI, results []*ParseResult) error {
+	facts := make(map[string]*FuncFacts)
+	for _, result := range results {
+		for _, f := range result.FuncFacts {
+			facts[FuncHandlerKey(result.PackageName, f.Name)] = f
+		}
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		methods := make([]string, 0, len(doc.Paths[path]))
+		for method := range doc.Paths[path] {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			op := doc.Paths[path][method]
+			ep := op.endpoint
+			response, err := c.getChatGPTResponse(buildOperationDocPrompt(method, path, ep, facts[ep.HandlerKey]))
+			if err != nil {
+				return err
+			}
+			response = strings.TrimSpace(response)
+			response = strings.TrimPrefix(response, "```yaml")
+			response = strings.TrimSuffix(response, "```")
+
+			var operationDoc struct {
+				Summary     string `yaml:"summary"`
+				Description string `yaml:"description"`
+			}
+			if err := yaml.Unmarshal([]byte(response), &operationDoc); err != nil {
+				fmt.Println("Operation doc Error parsing YAML:", err)
+				continue
+			}
+			if operationDoc.Summary != "" {
+				op.Summary = operationDoc.Summary
+			}
+			op.Description = operationDoc.Description
+		}
+	}
+	return nil
 }
 
 func (c *ChatGPTClient) GenNodeDoc(nodeName, fileContent string) (string, error) {
@@ -0,0 +1,113 @@
+package code
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// citationRegex 回答中 path:start-end 或 path:line 形式的引用
+var citationRegex = regexp.MustCompile("`?([A-Za-z0-9_@./-]+\\.[A-Za-z0-9]+):(\\d+)(?:-(\\d+))?`?")
+
+// invalidCitationMark 标记无法验证的引用
+const invalidCitationMark = "[无效引用]"
+
+// NumberLines 为源码的每一行加上行号，start 为第一行的行号
+func NumberLines(content string, start int) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	width := len(strconv.Itoa(start + len(lines) - 1))
+	var strBuilder strings.Builder
+	for i, line := range lines {
+		strBuilder.WriteString(fmt.Sprintf("%*d| %s\n", width, start+i, line))
+	}
+	return strBuilder.String()
+}
+
+// numberedLineRegex NumberLines 输出的带行号的行
+var numberedLineRegex = regexp.MustCompile(`(?m)^ *(\d+)\| `)
+
+// LineRange 发送给模型的源码行，包含首尾两行
+type LineRange struct {
+	Start int `json:"start" yaml:"start"`
+	End   int `json:"end" yaml:"end"`
+}
+
+// NumberedLineRanges 找出 NumberLines 输出的文本中出现的行号，合并为连续的区间
+func NumberedLineRanges(text string) []LineRange {
+	var ranges []LineRange
+	for _, match := range numberedLineRegex.FindAllStringSubmatch(text, -1) {
+		line, _ := strconv.Atoi(match[1])
+		if n := len(ranges); n > 0 && line >= ranges[n-1].Start && line <= ranges[n-1].End+1 {
+			ranges[n-1].End = max(ranges[n-1].End, line)
+			continue
+		}
+		ranges = append(ranges, LineRange{Start: line, End: line})
+	}
+	return ranges
+}
+
+// coveredBy 引用的行是否都在发送的区间内
+func (c *Citation) coveredBy(ranges []LineRange) bool {
+	for _, r := range ranges {
+		if c.StartLine >= r.Start && c.EndLine <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyCitations 找出回答中的所有引用并逐个验证：文件必须是已知的文件，行号必须在 lineCount 返回的行数以内，
+// sent 不为 nil 时引用的行还必须是发送给模型的源码行
+//
+// 只有能解析为已知文件、或者写成提示词要求的反引号形式且扩展名与已知文件相同的文本才视为引用，
+// 其他 host:port、name.ext:N 形式的文本保持不变。
+// 返回的文本中引用的路径被替换为解析后的路径，无效的引用后面追加标记
+func VerifyCitations(text string, resolver *FileResolver, lineCount func(file string) (int, error), sent map[string][]LineRange) (string, []*Citation) {
+	var citations []*Citation
+	seen := make(map[string]*Citation)
+	verified := citationRegex.ReplaceAllStringFunc(text, func(match string) string {
+		groups := citationRegex.FindStringSubmatch(match)
+		file, known := resolver.Known(groups[1])
+		quoted := strings.HasPrefix(match, "`") && strings.HasSuffix(match, "`")
+		if !known && !(quoted && resolver.HasExtension(groups[1])) {
+			return match
+		}
+
+		start, _ := strconv.Atoi(groups[2])
+		end := start
+		if groups[3] != "" {
+			end, _ = strconv.Atoi(groups[3])
+		}
+		citation := &Citation{File: groups[1], StartLine: start, EndLine: end, Text: strings.Trim(match, "`")}
+		if !known {
+			resolved, err := resolver.Resolve(groups[1])
+			if err != nil {
+				citation.Invalid = err.Error()
+			}
+			file = resolved
+		}
+		if citation.Invalid == "" {
+			citation.File = file
+			if lines, err := lineCount(file); err != nil {
+				citation.Invalid = err.Error()
+			} else if start < 1 || end < start || end > lines {
+				citation.Invalid = fmt.Sprintf("lines %d-%d out of range, file has %d lines", start, end, lines)
+			} else if sent != nil && !citation.coveredBy(sent[file]) {
+				citation.Invalid = fmt.Sprintf("lines %d-%d were not sent to the model", start, end)
+			}
+		}
+
+		key := citation.String()
+		if _, ok := seen[key]; !ok {
+			seen[key] = citation
+			citations = append(citations, citation)
+		}
+		replaced := strings.Replace(match, groups[1], citation.File, 1)
+		if citation.Invalid != "" {
+			return replaced + invalidCitationMark
+		}
+		return replaced
+	})
+	return verified, citations
+}
@@ -0,0 +1,69 @@
+package code
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNumberLines(t *testing.T) {
+	got := NumberLines("a\nb\n", 9)
+	if want := " 9| a\n10| b\n"; got != want {
+		t.Errorf("NumberLines = %q, want %q", got, want)
+	}
+}
+
+func TestVerifyCitations(t *testing.T) {
+	resolver := NewFileResolver("/src", []string{"internal/user/service.go", "cmd/main.go", "internal/order/big.go"})
+	lines := map[string]int{"internal/user/service.go": 50, "cmd/main.go": 20, "internal/order/big.go": 400}
+	lineCount := func(file string) (int, error) {
+		n, ok := lines[file]
+		if !ok {
+			return 0, fmt.Errorf("not found")
+		}
+		return n, nil
+	}
+	// big.go 只发送了两个符号的源码
+	sent := map[string][]LineRange{
+		"internal/user/service.go": {{Start: 1, End: 50}},
+		"cmd/main.go":              {{Start: 1, End: 20}},
+		"internal/order/big.go":    {{Start: 10, End: 30}, {Start: 100, End: 120}},
+	}
+
+	text := "校验密码 `internal/user/service.go:10-20`，入口 user/service.go:30，" +
+		"启动 cmd/main.go:15-40，配置 `config/app.go:1-3`，下单 `big.go:105-110`，未发送 `big.go:50-60`，" +
+		"服务地址 api.example.com:443，日志 app.log:12，重复 internal/user/service.go:10-20"
+	verified, citations := VerifyCitations(text, resolver, lineCount, sent)
+
+	want := "校验密码 `internal/user/service.go:10-20`，入口 internal/user/service.go:30，" +
+		"启动 cmd/main.go:15-40[无效引用]，配置 `config/app.go:1-3`[无效引用]，下单 `internal/order/big.go:105-110`，未发送 `internal/order/big.go:50-60`[无效引用]，" +
+		"服务地址 api.example.com:443，日志 app.log:12，重复 internal/user/service.go:10-20"
+	if verified != want {
+		t.Errorf("verified text =\n%s\nwant\n%s", verified, want)
+	}
+	var got []string
+	for _, c := range citations {
+		got = append(got, fmt.Sprintf("%s valid=%v", c, c.Invalid == ""))
+	}
+	wantCitations := []string{
+		"internal/user/service.go:10-20 valid=true",
+		"internal/user/service.go:30-30 valid=true",
+		"cmd/main.go:15-40 valid=false",
+		"config/app.go:1-3 valid=false",
+		"internal/order/big.go:105-110 valid=true",
+		"internal/order/big.go:50-60 valid=false",
+	}
+	if !reflect.DeepEqual(got, wantCitations) {
+		t.Errorf("citations = %v, want %v", got, wantCitations)
+	}
+	if citations[5].Text != "big.go:50-60" {
+		t.Errorf("citation text = %q, want the text from the answer", citations[5].Text)
+	}
+}
+
+func TestNumberedLineRanges(t *testing.T) {
+	text := "// Do (第 3-4 行)\n" + NumberLines("a\nb", 3) + "\n" + NumberLines("c\nd\ne", 5) + NumberLines("x", 98) + "...(内容过长，已截断)\n"
+	if want := []LineRange{{Start: 3, End: 7}, {Start: 98, End: 98}}; !reflect.DeepEqual(NumberedLineRanges(text), want) {
+		t.Errorf("ranges = %v, want %v", NumberedLineRanges(text), want)
+	}
+}
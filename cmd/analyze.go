@@ -6,15 +6,46 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var (
-	dir       string
-	apiToken  string
-	outputDir string
+	dir             string
+	apiToken        string
+	outputDir       string
+	generatedPolicy string
+	withTests       bool
+	sinceRev        string
+	stagedOnly      bool
+	fileLimits      code.FileLimits
+	oversizedPolicy string
+	languageNames   []string
+	withHierarchy   bool
+	withEmbeddings  bool
+	withUnexported  bool
+
+	// changedFiles 增量分析时需要重新分析的文件，为 nil 时分析全部文件
+	changedFiles map[string]bool
+	// workspace 分析目录中的 Go 模块
+	workspace *code.Workspace
+	// report 本次运行的报告，记录每个文件的处理方式
+	report *code.RunReport
+	// analyzeRoot 被分析的目录，结果中的路径均相对于该目录
+	analyzeRoot string
+	// resultIndex 源文件与结果文件的对应关系
+	resultIndex *code.ResultIndex
+)
+
+const (
+	// generatedGroupPrefix 生成代码合并摘要使用的虚拟路径前缀，不会与源码目录冲突
+	generatedGroupPrefix = "@generated/"
+	// testCoverageFileName 测试关联结果文件
+	testCoverageFileName = "tests.yaml"
 )
 
 // analyzeCmd 定义了分析命令
@@ -34,6 +65,18 @@ func init() {
 	analyzeCmd.Flags().StringVarP(&dir, "dir", "d", "", "Directory to analyze (required)")
 	analyzeCmd.Flags().StringVarP(&apiToken, "token", "t", "", "API token for AI analysis (required)")
 	analyzeCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "./result", "总结文件输出地方")
+	analyzeCmd.Flags().StringVar(&generatedPolicy, "generated", code.GeneratedPolicyStatic, "生成代码和第三方代码的处理方式: skip | static(静态摘要) | group(按生成器合并为一条摘要)")
+	analyzeCmd.Flags().BoolVar(&withTests, "with-tests", false, "同时分析 _test.go 测试文件, 并关联测试函数与被测函数")
+	analyzeCmd.Flags().StringVar(&sinceRev, "since", "", "只分析相对于该 git 版本变更的文件(包括未提交的修改和未跟踪的新文件), 并更新已有的分析结果")
+	analyzeCmd.Flags().BoolVar(&stagedOnly, "staged", false, "只分析 git 暂存区中变更的文件, 并更新已有的分析结果")
+	analyzeCmd.Flags().IntVar(&fileLimits.MaxBytes, "max-file-bytes", 128*1024, "发送给 AI 的单个文件最大字节数, 0 表示不限制")
+	analyzeCmd.Flags().IntVar(&fileLimits.MaxLines, "max-lines", 3000, "发送给 AI 的单个文件最大行数, 0 表示不限制")
+	analyzeCmd.Flags().StringVar(&oversizedPolicy, "oversized", code.OversizedPolicyStatic, "超出大小限制的文件的处理方式: skip | chunk(切分后分段分析) | static(静态摘要)")
+	analyzeCmd.Flags().StringSliceVar(&languageNames, "languages", []string{code.LanguageGo}, "需要分析的语言, 可选 go, python, sql")
+	analyzeCmd.Flags().BoolVar(&withHierarchy, "hierarchy", false, "根据文件摘要生成包摘要和架构概览(每个包和概览各需要一次 AI 调用), 问答时据此逐级选择相关的包和文件")
+	analyzeCmd.Flags().BoolVar(&withEmbeddings, "embeddings", false, "为文件、包和符号的摘要生成向量, 用于语义检索(search 命令和问答)")
+	analyzeCmd.Flags().BoolVar(&withUnexported, "with-unexported", false, "静态解析时同时记录未导出的函数、包级变量和函数体信息, 用于检索索引和测试关联")
+	addWalkFlags(analyzeCmd)
 
 	// 必须参数检查
 	err := analyzeCmd.MarkFlagRequired("dir")
@@ -51,82 +94,693 @@ func init() {
 // run 主要逻辑
 func run(directory, token string) error {
 	aiClient := code.NewChatGPTClient(token)
+	parser := code.NewParserWithOptions(code.ParserOptions{IncludeUnexported: withUnexported})
 	var count int
+	var parseResults []*code.ParseResult
+
+	switch generatedPolicy {
+	case code.GeneratedPolicySkip, code.GeneratedPolicyStatic, code.GeneratedPolicyGroup:
+	default:
+		return fmt.Errorf("unknown generated policy: %s", generatedPolicy)
+	}
+	switch oversizedPolicy {
+	case code.OversizedPolicySkip, code.OversizedPolicyChunk, code.OversizedPolicyStatic:
+	default:
+		return fmt.Errorf("unknown oversized policy: %s", oversizedPolicy)
+	}
+	if sinceRev != "" && stagedOnly {
+		return fmt.Errorf("--since and --staged cannot be used together")
+	}
+	languages, err := code.LookupLanguages(languageNames, parser)
+	if err != nil {
+		return err
+	}
+	report = code.NewRunReport(directory)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output dir: %v", err)
+	}
+	analyzeRoot = directory
+	resultIndex, err = code.LoadResultIndex(filepath.Join(outputDir, code.ResultIndexFileName))
+	if err != nil {
+		return fmt.Errorf("failed to load result index: %v", err)
+	}
+	if resultIndex.Root, err = filepath.Abs(directory); err != nil {
+		return err
+	}
+	incremental := sinceRev != "" || stagedOnly
+	if !incremental {
+		// 全量分析时总结只包含本次运行的结果
+		resultIndex.Files = make(map[string]string)
+	}
+	opts, err := newWalkOptions(directory)
+	if err != nil {
+		return err
+	}
+	// 无法读取的目录和失效的符号链接记录到运行报告后继续遍历
+	opts.OnError = func(path string, err error) {
+		log.Printf("Skip %s: %v\n", path, err)
+		report.Record(path, code.ReportActionSkipped, err.Error())
+	}
+	filter := opts.Filter
+	filter.SkipGenerated = generatedPolicy == code.GeneratedPolicySkip
+	filter.IncludeTests = withTests
+
+	workspace, err = code.DiscoverModules(directory, opts)
+	if err != nil {
+		return fmt.Errorf("failed to discover go modules: %v", err)
+	}
+
+	if incremental {
+		if err := prepareIncremental(directory); err != nil {
+			return err
+		}
+	}
+	groups := make(map[string]*code.GeneratedGroup)
+	var testFiles, goFiles []string
 
 	// 遍历目录并处理每个文件
-	err := code.WalkDir(directory, func(path string) {
-		processFile(path, aiClient)
-		count++
+	err = code.WalkLanguages(directory, opts, languages, func(path string, lang code.Language) {
+		if lang.Name() == code.LanguageGo {
+			goFiles = append(goFiles, path)
+		}
+		// 测试文件需要先收集全部生产代码才能关联，延后处理
+		if code.IsTestFile(path) {
+			testFiles = append(testFiles, path)
+			return
+		}
+		if parseResult := processFile(path, lang, aiClient, groups); parseResult != nil {
+			parseResults = append(parseResults, parseResult)
+		}
+		if needsAnalysis(path) {
+			count++
+		}
 	})
 
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 		return err
 	}
-	fmt.Printf("Processed %d files\n", count)
+
+	// 按生成器合并生成代码的摘要
+	for _, generator := range sortedGroupNames(groups) {
+		rawResult, yamlResult, err := code.BuildGeneratedGroupSummary(groups[generator])
+		if err != nil {
+			log.Printf("Failed to summarize generated files of %s: %v\n", generator, err)
+			continue
+		}
+		saveFileResult(generatedGroupPrefix+generator, rawResult, &yamlResult)
+	}
+
+	if withTests {
+		count += processTestFiles(testFiles, parseResults, aiClient, parser)
+	} else if !incremental {
+		// 全量分析且不分析测试时，上一次的测试关联结果已经过期
+		if err := os.Remove(filepath.Join(outputDir, testCoverageFileName)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove stale test coverage: %v\n", err)
+		}
+	}
+
+	// 解析 .proto 文件，不调用 AI
+	var protos []*code.ProtoFile
+	err = code.WalkProtoDir(directory, opts, func(path string) {
+		if proto := processProtoFile(path); proto != nil {
+			protos = append(protos, proto)
+		}
+		if needsAnalysis(path) {
+			count++
+		}
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return err
+	}
+
+	// 汇总项目中所有的路由
+	if err := saveEndpoints(code.ResolveEndpointTypes(parseResults)); err != nil {
+		log.Printf("Failed to save endpoints: %v\n", err)
+	}
+	// 关联 proto 定义、生成代码和服务实现
+	if err := saveProtoLinks(code.LinkProto(protos, parseResults)); err != nil {
+		log.Printf("Failed to save proto links: %v\n", err)
+	}
+	// 生成工作区索引
+	if err := saveWorkspaceIndex(goFiles); err != nil {
+		log.Printf("Failed to save workspace index: %v\n", err)
+	}
+	if err := resultIndex.Save(filepath.Join(outputDir, code.ResultIndexFileName)); err != nil {
+		log.Printf("Failed to save result index: %v\n", err)
+	}
+	// 根据保存的分析结果重新生成总结文件
+	entries := code.LoadSummaryEntries(outputDir, resultIndex, func(source string, err error) {
+		log.Printf("Failed to load result of %s: %v\n", source, err)
+	})
+	if err := renderSummaries(directory, entries); err != nil {
+		log.Printf("Failed to render summary: %v\n", err)
+	}
+	if withHierarchy {
+		if err := saveSummaryTree(entries, aiClient); err != nil {
+			log.Printf("Failed to save summary tree: %v\n", err)
+		}
+	}
+	// 建立问答使用的本地检索索引
+	if err := saveSearchIndex(entries, parseResults); err != nil {
+		log.Printf("Failed to save search index: %v\n", err)
+	}
+	if withEmbeddings {
+		if err := saveVectorStore(entries, parseResults, aiClient); err != nil {
+			log.Printf("Failed to save vector store: %v\n", err)
+		}
+	}
+	if err := report.Save(filepath.Join(outputDir, "report.yaml")); err != nil {
+		log.Printf("Failed to save run report: %v\n", err)
+	}
+	fmt.Printf("Processed %d files (analyzed %d, chunked %d, static %d, skipped %d, failed %d)\n", count,
+		report.Count(code.ReportActionAnalyzed), report.Count(code.ReportActionChunked), report.Count(code.ReportActionStatic),
+		report.Count(code.ReportActionSkipped), report.Count(code.ReportActionFailed))
 	return nil
 }
 
-// 处理单个文件
-func processFile(path string, aiClient *code.ChatGPTClient) {
-	fmt.Println("Processing file:", path)
+// 增量分析：计算 git 变更的文件，并从结果索引中删除已删除的文件以及会重新生成的合并摘要
+//
+// 未变更的文件仍会静态解析，用于生成完整的 endpoints.yaml、proto.yaml 和 tests.yaml，但不会调用 AI
+func prepareIncremental(directory string) error {
+	changes, err := code.GitChangedFiles(directory, sinceRev, stagedOnly)
+	if err != nil {
+		return err
+	}
+	changedFiles = make(map[string]bool)
+	for _, path := range changes.Changed {
+		changedFiles[path] = true
+	}
+	for _, path := range changes.Deleted {
+		name := relPath(path)
+		resultIndex.Remove(name)
+		if err := os.Remove(resultFilePath(name)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove result of deleted file %s: %v\n", path, err)
+		}
+	}
+	fmt.Printf("Incremental analysis: %d changed, %d deleted files\n", len(changes.Changed), len(changes.Deleted))
+
+	// 生成代码的合并摘要会重新生成，删除旧的记录避免生成器不再存在时残留
+	if generatedPolicy == code.GeneratedPolicyGroup {
+		for _, name := range resultIndex.Sources() {
+			if strings.HasPrefix(name, generatedGroupPrefix) {
+				resultIndex.Remove(name)
+			}
+		}
+	}
+	return nil
+}
+
+// needsAnalysis 判断文件是否需要重新分析
+func needsAnalysis(path string) bool {
+	return changedFiles == nil || changedFiles[path]
+}
+
+// 处理 .proto 文件，生成静态摘要
+func processProtoFile(path string) *code.ProtoFile {
+	proto, err := code.ParseProtoFile(path)
+	if err != nil {
+		log.Printf("Failed to parse proto file %s: %v\n", path, err)
+		return nil
+	}
+	if !needsAnalysis(path) {
+		return proto
+	}
+	fmt.Println("Processing proto file:", path)
+
+	rawResult, yamlResult, err := code.BuildProtoSummary(proto)
+	if err != nil {
+		log.Printf("Failed to summarize proto file %s: %v\n", path, err)
+		report.Record(path, code.ReportActionFailed, err.Error())
+		return proto
+	}
+	report.Record(path, code.ReportActionStatic, "proto definition")
+	saveFileResult(path, rawResult, &yamlResult)
+	return proto
+}
+
+// 处理测试文件：关联测试函数与被测函数，AI 分析测试行为，并汇总未被测试的导出函数
+func processTestFiles(paths []string, parseResults []*code.ParseResult, aiClient *code.ChatGPTClient, parser *code.Parser) int {
+	var results []*code.ParseResult
+	resultByPath := make(map[string]*code.ParseResult)
+	for _, path := range paths {
+		parseResult, err := parser.ParseByFile(path)
+		if err != nil {
+			log.Printf("Failed to parse test file %s: %v\n", path, err)
+			continue
+		}
+		results = append(results, parseResult)
+		resultByPath[path] = parseResult
+	}
+	coverage := code.LinkTests(parseResults, results)
+	targets := make(map[string]map[string][]string)
+	for _, link := range coverage.Links {
+		if targets[link.File] == nil {
+			targets[link.File] = make(map[string][]string)
+		}
+		targets[link.File][link.Test] = link.Targets
+	}
+
+	count := 0
+	for _, path := range paths {
+		if !needsAnalysis(path) {
+			continue
+		}
+		count++
+		fmt.Println("Processing test file:", path)
+		fileContent, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Failed to read file %s: %v\n", path, err)
+			continue
+		}
+		rawAiResponse, yamlResult, ok := analyzeContent(path, fileContent, resultByPath[path], aiClient.AIAnalysisTestCode)
+		if !ok {
+			continue
+		}
+		if len(targets[path]) > 0 {
+			rawAiResponse, err = mergeTestTargets(rawAiResponse, &yamlResult, targets[path])
+			if err != nil {
+				log.Printf("Failed to merge test targets for %s: %v\n", path, err)
+			}
+		}
+		saveFileResult(path, rawAiResponse, &yamlResult)
+	}
+
+	if err := saveTestCoverage(coverage); err != nil {
+		log.Printf("Failed to save test coverage: %v\n", err)
+	}
+	return count
+}
+
+// 处理单个文件，返回静态解析结果(解析失败时为 nil)
+func processFile(path string, lang code.Language, aiClient *code.ChatGPTClient, groups map[string]*code.GeneratedGroup) *code.ParseResult {
+	if needsAnalysis(path) {
+		fmt.Println("Processing file:", path)
+	}
+
+	parseResult, err := lang.Parse(path)
+	if err != nil {
+		log.Printf("Failed to parse file %s: %v\n", path, err)
+		// protobuf 生成代码无法解析时没有可用的静态摘要，也不发送给 AI
+		if code.IsProtoGeneratedFile(path) {
+			if needsAnalysis(path) {
+				report.Record(path, code.ReportActionSkipped, "failed to parse generated protobuf code: "+err.Error())
+			}
+			return nil
+		}
+	}
+
+	// 生成代码和第三方代码使用静态摘要，不发送给 AI
+	if generated, err := code.DetectGeneratedFile(path); err == nil && generated.Generated {
+		fmt.Printf("Generated by %s (%s)\n", generated.Generator, generated.Reason)
+		if generatedPolicy == code.GeneratedPolicyGroup {
+			group, ok := groups[generated.Generator]
+			if !ok {
+				group = &code.GeneratedGroup{Generator: generated.Generator}
+				groups[generated.Generator] = group
+			}
+			group.Files = append(group.Files, path)
+			if parseResult != nil {
+				group.Results = append(group.Results, parseResult)
+			}
+			if needsAnalysis(path) {
+				report.Record(path, code.ReportActionStatic, "grouped with files generated by "+generated.Generator)
+			}
+			return parseResult
+		}
+		if !needsAnalysis(path) {
+			return parseResult
+		}
+
+		rawResult, yamlResult, err := code.BuildStaticSummary(path, code.GeneratedDescription(generated, parseResult), parseResult)
+		if err != nil {
+			log.Printf("Failed to summarize generated file %s: %v\n", path, err)
+			report.Record(path, code.ReportActionFailed, err.Error())
+			return parseResult
+		}
+		report.Record(path, code.ReportActionStatic, "generated by "+generated.Generator)
+		saveFileResult(path, rawResult, &yamlResult)
+		return parseResult
+	}
+
+	// 增量分析时未变更的文件只做静态解析
+	if !needsAnalysis(path) {
+		return parseResult
+	}
 
 	// 读取文件内容
 	fileContent, err := os.ReadFile(path)
 	if err != nil {
 		log.Printf("Failed to read file %s: %v\n", path, err)
-		return
+		return parseResult
 	}
 
 	// 调用 AI 进行代码分析
-	rawAiResponse, yamlResult, err := aiClient.AIAnalysisCode(path, string(fileContent))
-	if err != nil {
-		log.Printf("AI analysis failed for %s: %v\n", path, err)
-		return
+	rawAiResponse, yamlResult, ok := analyzeContent(path, fileContent, parseResult, func(filename, content string) (string, code.ParsedYAML, error) {
+		return aiClient.AIAnalysisLanguage(lang, filename, content)
+	})
+	if !ok {
+		return parseResult
+	}
+	if lang.Name() != code.LanguageGo {
+		yamlResult.Language = lang.Name()
+		rawAiResponse, err = appendYAMLBlock(rawAiResponse, map[string]string{"language": lang.Name()})
+		if err != nil {
+			log.Printf("Failed to tag language for %s: %v\n", path, err)
+		}
+	}
+
+	// 合并静态识别到的路由
+	if parseResult != nil && len(parseResult.Endpoints) > 0 {
+		rawAiResponse, err = mergeStaticEndpoints(rawAiResponse, parseResult.Endpoints)
+		if err != nil {
+			log.Printf("Failed to merge endpoints for %s: %v\n", path, err)
+		}
+		yamlResult.StaticEndpoints = parseResult.Endpoints
+	}
+
+	saveFileResult(path, rawAiResponse, &yamlResult)
+	return parseResult
+}
+
+// 检查文件内容后调用 AI 分析：二进制文件跳过，超大文件按 --oversized 策略跳过、生成静态摘要或切分后分段分析
+//
+// 返回 false 表示没有 AI 分析结果需要保存(跳过、失败或已保存静态摘要)
+func analyzeContent(path string, content []byte, parseResult *code.ParseResult, analyze func(filename, code string) (string, code.ParsedYAML, error)) (string, code.ParsedYAML, bool) {
+	check := code.CheckFileContent(content, fileLimits)
+	if check.Binary {
+		fmt.Printf("Skip %s: %s\n", path, check.Reason)
+		report.Record(path, code.ReportActionSkipped, check.Reason)
+		return "", code.ParsedYAML{}, false
+	}
+
+	if !check.Oversized {
+		rawAiResponse, yamlResult, err := analyze(path, string(content))
+		if err != nil {
+			log.Printf("AI analysis failed for %s: %v\n", path, err)
+			report.Record(path, code.ReportActionFailed, err.Error())
+			return "", code.ParsedYAML{}, false
+		}
+		report.Record(path, code.ReportActionAnalyzed, "")
+		return rawAiResponse, yamlResult, true
+	}
+
+	fmt.Printf("Oversized %s: %s, policy %s\n", path, check.Reason, oversizedPolicy)
+	switch oversizedPolicy {
+	case code.OversizedPolicySkip:
+		report.Record(path, code.ReportActionSkipped, check.Reason)
+		return "", code.ParsedYAML{}, false
+
+	case code.OversizedPolicyChunk:
+		chunks := code.SplitChunks(string(content), fileLimits)
+		raws := make([]string, 0, len(chunks))
+		results := make([]code.ParsedYAML, 0, len(chunks))
+		for i, chunk := range chunks {
+			rawAiResponse, yamlResult, err := analyze(fmt.Sprintf("%s (第 %d/%d 段)", path, i+1, len(chunks)), chunk)
+			if err != nil {
+				log.Printf("AI analysis failed for %s chunk %d: %v\n", path, i+1, err)
+				report.Record(path, code.ReportActionFailed, fmt.Sprintf("%s; chunk %d/%d: %v", check.Reason, i+1, len(chunks), err))
+				return "", code.ParsedYAML{}, false
+			}
+			raws = append(raws, rawAiResponse)
+			results = append(results, yamlResult)
+		}
+		report.Record(path, code.ReportActionChunked, fmt.Sprintf("%s; %d chunks", check.Reason, len(chunks)))
+		rawAiResponse, yamlResult := code.MergeChunkResults(raws, results)
+		return rawAiResponse, yamlResult, true
+
+	default:
+		rawResult, yamlResult, err := code.BuildStaticSummary(path, code.OversizedDescription(check, parseResult), parseResult)
+		if err != nil {
+			log.Printf("Failed to summarize oversized file %s: %v\n", path, err)
+			report.Record(path, code.ReportActionFailed, err.Error())
+			return "", code.ParsedYAML{}, false
+		}
+		report.Record(path, code.ReportActionStatic, check.Reason)
+		saveFileResult(path, rawResult, &yamlResult)
+		return "", code.ParsedYAML{}, false
 	}
+}
+
+// 保存单个文件的分析结果，总结文件在运行结束时根据保存的结果生成
+func saveFileResult(path, rawResult string, yamlResult *code.ParsedYAML) {
+	// 标记文件所属的模块和包导入路径
+	rawResult = tagModule(path, rawResult, yamlResult)
 
-	// 生成文件名并保存分析结果
-	if err := saveAIResult(path, rawAiResponse); err != nil {
+	// 结果和总结文件中使用相对于分析目录的路径
+	if err := saveAIResult(relPath(path), rawResult); err != nil {
 		log.Printf("Failed to save AI result for %s: %v\n", path, err)
-		return
 	}
+}
 
-	// 更新总结文件
-	if err := updateSummaryFile(path, &yamlResult); err != nil {
-		log.Printf("Failed to update summary for %s: %v\n", path, err)
-		return
+// 根据 go.mod 设置文件的模块路径和包导入路径，并追加到 YAML 中
+func tagModule(path, rawResult string, yamlResult *code.ParsedYAML) string {
+	if workspace == nil {
+		return rawResult
+	}
+	// 合并摘要等虚拟路径以及非 Go 文件不属于任何模块
+	if _, err := os.Stat(path); err != nil || filepath.Ext(path) != ".go" {
+		return rawResult
+	}
+	mod := workspace.ModuleFor(path)
+	if mod == nil {
+		return rawResult
+	}
+	yamlResult.ModulePath = mod.Path
+	yamlResult.ImportPath = workspace.ImportPath(path)
+	tagged, err := appendYAMLBlock(rawResult, map[string]string{"module_path": yamlResult.ModulePath, "import_path": yamlResult.ImportPath})
+	if err != nil {
+		return rawResult
 	}
+	return tagged
 }
 
-// 保存 AI 分析结果到文件
-func saveAIResult(path, rawAiResponse string) error {
-	resultPath := filepath.Join(outputDir, strings.ReplaceAll(path, "/", "|")+".yaml")
+// 将静态信息追加到 AI 输出的 YAML 中，重新读取结果时与 AI 的输出合并
+func appendYAMLBlock(rawResult string, v interface{}) (string, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return rawResult, err
+	}
+	return strings.TrimRight(rawResult, "\n") + "\n\n" + string(data), nil
+}
+
+// 保存工作区索引，没有任何 go.mod 时不生成
+func saveWorkspaceIndex(files []string) error {
+	if len(workspace.Modules) == 0 {
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "workspace.md"), []byte(workspace.Index(files)), 0644); err != nil {
+		return fmt.Errorf("error writing workspace index: %v", err)
+	}
+	return nil
+}
+
+// 按生成器名称排序，保证输出顺序稳定
+func sortedGroupNames(groups map[string]*code.GeneratedGroup) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 将静态识别的路由追加到 AI 输出的 YAML 中
+func mergeStaticEndpoints(rawAiResponse string, endpoints []*code.Endpoint) (string, error) {
+	return appendYAMLBlock(rawAiResponse, map[string][]*code.Endpoint{"static_endpoints": endpoints})
+}
+
+// 将静态关联的被测函数追加到 AI 输出的 YAML 中
+func mergeTestTargets(rawAiResponse string, yamlResult *code.ParsedYAML, targets map[string][]string) (string, error) {
+	yamlResult.TestTargets = targets
+	return appendYAMLBlock(rawAiResponse, map[string]map[string][]string{"test_targets": targets})
+}
+
+// 保存测试关联结果，未被测试的导出函数会在生成总结文件时列出
+func saveTestCoverage(coverage *code.TestCoverage) error {
+	for _, link := range coverage.Links {
+		link.File = relPath(link.File)
+	}
+	for _, fn := range coverage.Untested {
+		fn.File = relPath(fn.File)
+	}
+	data, err := yaml.Marshal(coverage)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, testCoverageFileName), data, 0644); err != nil {
+		return fmt.Errorf("error writing tests file: %v", err)
+	}
+	return nil
+}
+
+// 保存项目中所有路由的汇总列表
+func saveEndpoints(endpoints []*code.Endpoint) error {
+	if len(endpoints) == 0 {
+		return nil
+	}
+	data, err := yaml.Marshal(endpoints)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "endpoints.yaml"), data, 0644); err != nil {
+		return fmt.Errorf("error writing endpoints file: %v", err)
+	}
+	return nil
+}
+
+// relPath 返回相对于分析目录、使用 / 分隔的路径，不在分析目录下的路径(例如生成代码的合并摘要)原样返回
+func relPath(path string) string {
+	rel, err := filepath.Rel(analyzeRoot, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// 单个文件分析结果的保存路径，输出目录中保持与源码相同的目录结构，例如 pkg/foo.go -> result/pkg/foo.go.yaml
+func resultFilePath(name string) string {
+	return filepath.Join(outputDir, filepath.FromSlash(name)+".yaml")
+}
+
+// 保存 AI 分析结果到文件，并记录到结果索引
+func saveAIResult(name, rawAiResponse string) error {
+	resultPath := resultFilePath(name)
+	if err := os.MkdirAll(filepath.Dir(resultPath), 0755); err != nil {
+		return fmt.Errorf("error creating result dir: %v", err)
+	}
 	if err := os.WriteFile(resultPath, []byte(rawAiResponse), 0644); err != nil {
 		return fmt.Errorf("error writing result file: %v", err)
 	}
+	resultIndex.Set(name, filepath.ToSlash(name)+".yaml")
 	return nil
 }
 
-// 更新总结文件
-func updateSummaryFile(path string, yamlResult *code.ParsedYAML) error {
-	var strBuilder strings.Builder
+// 保存 proto 服务、消息与 Go 代码的关联关系
+func saveProtoLinks(links *code.ProtoLinks) error {
+	if len(links.Services) == 0 && len(links.Messages) == 0 {
+		return nil
+	}
+	data, err := yaml.Marshal(links)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "proto.yaml"), data, 0644); err != nil {
+		return fmt.Errorf("error writing proto file: %v", err)
+	}
+	return nil
+}
+
+// 根据结果索引中的分析结果重新生成总结文件和模块摘要，条目按包和路径排序
+func renderSummaries(directory string, entries []*code.SummaryEntry) error {
+	meta := code.SummaryMeta{
+		Root:          resultIndex.Root,
+		Model:         code.ChatGPTModel,
+		PromptVersion: code.PromptVersion,
+		Date:          time.Now(),
+	}
+	if commit, err := code.GitHeadCommit(directory); err == nil {
+		meta.Commit = commit
+	}
+
+	modules := make(map[string][]*code.SummaryEntry)
+	for _, entry := range entries {
+		if entry.Result.ModulePath != "" {
+			modules[entry.Result.ModulePath] = append(modules[entry.Result.ModulePath], entry)
+		}
+	}
+
+	var untested []*code.UntestedFunc
+	if data, err := os.ReadFile(filepath.Join(outputDir, testCoverageFileName)); err == nil {
+		var coverage code.TestCoverage
+		if err := yaml.Unmarshal(data, &coverage); err != nil {
+			log.Printf("Failed to load test coverage: %v\n", err)
+		}
+		untested = coverage.Untested
+	}
+
+	if err := os.WriteFile(filepath.Join(outputDir, "all.md"), []byte(code.RenderSummary(meta, entries, untested)), 0644); err != nil {
+		return fmt.Errorf("failed to write summary file: %v", err)
+	}
 
-	strBuilder.WriteString(fmt.Sprintf("文件名: %s\n", path))
-	strBuilder.WriteString(fmt.Sprintf("功能: %s\n", yamlResult.FunctionDescription))
-	strBuilder.WriteString(fmt.Sprintf("包名: %s\n", yamlResult.FileInfo.PackageName))
-	strBuilder.WriteString("依赖导入项目: ")
-	strBuilder.WriteString(strings.Join(yamlResult.FileInfo.Imports, ","))
-	strBuilder.WriteString("\n---\n")
+	// 模块摘要全部重新生成，删除已经不存在的模块
+	modulesDir := filepath.Join(outputDir, "modules")
+	if err := os.RemoveAll(modulesDir); err != nil {
+		return fmt.Errorf("failed to clean module summaries: %v", err)
+	}
+	if len(modules) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(modulesDir, 0755); err != nil {
+		return fmt.Errorf("failed to create summary dir: %v", err)
+	}
+	for modulePath, moduleEntries := range modules {
+		summary := code.RenderSummary(meta, moduleEntries, nil)
+		if err := os.WriteFile(filepath.Join(modulesDir, code.ModuleSummaryName(modulePath)), []byte(summary), 0644); err != nil {
+			return fmt.Errorf("failed to write module summary: %v", err)
+		}
+	}
+	return nil
+}
 
-	// 追加写入总结文件
-	file, err := os.OpenFile(outputDir+"/all.md", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// 根据文件摘要生成包摘要和架构概览，内容未变化的包复用上一次的摘要
+func saveSummaryTree(entries []*code.SummaryEntry, aiClient *code.ChatGPTClient) error {
+	treePath := filepath.Join(outputDir, code.SummaryTreeFileName)
+	previous, err := code.LoadSummaryTree(treePath)
 	if err != nil {
-		return fmt.Errorf("failed to open summary file: %v", err)
+		log.Printf("Failed to load previous summary tree: %v\n", err)
+	}
+	tree, err := code.BuildSummaryTree(entries, previous, func(pkg, fileSummaries string) (string, error) {
+		fmt.Println("Summarizing package:", pkg)
+		return aiClient.AISummarizePackage(pkg, fileSummaries)
+	}, func(packages string) (string, error) {
+		fmt.Println("Summarizing architecture")
+		return aiClient.AISummarizeArchitecture(packages)
+	})
+	// 部分包失败时仍然保存已经生成的摘要，失败的包下一次运行时重新生成
+	if saveErr := tree.Save(treePath); saveErr != nil {
+		return fmt.Errorf("error writing summary tree: %v", saveErr)
+	}
+	if saveErr := os.WriteFile(filepath.Join(outputDir, code.ArchitectureFileName), []byte(tree.Markdown()), 0644); saveErr != nil {
+		return fmt.Errorf("error writing architecture overview: %v", saveErr)
 	}
-	defer file.Close()
+	return err
+}
 
-	if _, err := file.WriteString(strBuilder.String()); err != nil {
-		return fmt.Errorf("failed to write to summary file: %v", err)
+// 根据文件摘要和静态解析结果建立本地检索索引
+func saveSearchIndex(entries []*code.SummaryEntry, parseResults []*code.ParseResult) error {
+	if err := code.BuildSearchIndex(entries, symbolsByPath(parseResults)).Save(filepath.Join(outputDir, code.SearchIndexFileName)); err != nil {
+		return fmt.Errorf("error writing search index: %v", err)
 	}
 	return nil
 }
+
+// 为文件、包和符号的摘要生成向量，文本未变化的条目复用已有的向量
+func saveVectorStore(entries []*code.SummaryEntry, parseResults []*code.ParseResult, aiClient *code.ChatGPTClient) error {
+	store, err := code.LoadFileVectorStore(filepath.Join(outputDir, code.VectorStoreFileName))
+	if err != nil {
+		return fmt.Errorf("failed to load vector store: %v", err)
+	}
+	tree, err := code.LoadSummaryTree(filepath.Join(outputDir, code.SummaryTreeFileName))
+	if err != nil {
+		log.Printf("Failed to load summary tree: %v\n", err)
+	}
+	items := code.BuildVectorItems(entries, tree, symbolsByPath(parseResults))
+	embedded, err := code.EmbedItems(store, items, aiClient)
+	fmt.Printf("Embedded %d of %d items\n", embedded, len(items))
+	// 部分失败时仍保存已经生成的向量
+	if saveErr := store.Save(); saveErr != nil {
+		return fmt.Errorf("error writing vector store: %v", saveErr)
+	}
+	return err
+}
+
+// 以相对于分析目录的路径索引静态解析结果
+func symbolsByPath(parseResults []*code.ParseResult) map[string]*code.ParseResult {
+	symbols := make(map[string]*code.ParseResult, len(parseResults))
+	for _, result := range parseResults {
+		symbols[relPath(result.FilePath)] = result
+	}
+	return symbols
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"bufio"
+	code "codetest"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	chatSessionID string
+	chatForce     bool
+)
+
+// chatCmd 多轮对话，会话保存在输出目录的 sessions 目录中
+//
+//	go run entry/main.go chat -t sk-xxx -s ./result/all.md
+//	go run entry/main.go chat -t sk-xxx -s ./result/all.md --session 20240101-120000
+var chatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Chat with AI about an analyzed repository, keeping retrieved files and answers in context",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runChat(apiToken)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(chatCmd)
+	chatCmd.Flags().StringVarP(&apiToken, "token", "t", "", "API token for AI analysis (required)")
+	chatCmd.Flags().StringVarP(&summaryFilePath, "summary-dir", "s", "./result/all.md", "总结文件输出地方")
+	chatCmd.Flags().StringVar(&chatSessionID, "session", "", "恢复保存的会话, 会话 ID 或会话文件路径")
+	chatCmd.Flags().BoolVar(&chatForce, "force", false, "会话的项目根目录与当前分析结果不一致时仍然恢复")
+	chatCmd.Flags().BoolVar(&streamAnswer, "stream", true, "边生成边输出回答, 正文中的引用在回答结束后才验证, 无效的引用按原文列在回答之后")
+	chatCmd.Flags().IntVar(&candidateLimit, "candidates", 30, "使用本地检索索引预选的候选文件数量, 0 表示不预选")
+	chatCmd.Flags().BoolVar(&withInternal, "with-internal", false, "分析文件时附带未导出函数的调用关系、字段读写和返回错误等静态信息")
+
+	err := chatCmd.MarkFlagRequired("token")
+	if err != nil {
+		log.Println("Error: token flag is required", err)
+		return
+	}
+}
+
+const chatHelp = `输入问题开始对话，或使用以下命令:
+  /files        列出已经分析过的文件和打开的文件
+  /open <path>  打开文件, 之后的问题会附带该文件的全文
+  /reset        清空对话历史和文件
+  /save [path]  保存会话(每轮问答后也会自动保存)
+  /help         显示帮助
+  /exit         退出`
+
+func runChat(token string) error {
+	aiClient, index, err := newQuestionClient(token)
+	if err != nil {
+		return err
+	}
+	root := ""
+	var known []string
+	if index != nil {
+		root, known = index.Root, index.Sources()
+	}
+
+	sessionDir := filepath.Join(filepath.Dir(summaryFilePath), "sessions")
+	session := code.NewChatSession(sessionDir, root)
+	if chatSessionID != "" {
+		path := chatSessionID
+		if !strings.HasSuffix(path, ".yaml") {
+			path = filepath.Join(sessionDir, chatSessionID+".yaml")
+		}
+		if session, err = code.LoadChatSession(path); err != nil {
+			return fmt.Errorf("failed to load session: %v", err)
+		}
+		// 在其他项目中恢复会话会把两个项目的文件混在同一段对话中
+		if session.Root != "" && root != "" && filepath.Clean(session.Root) != filepath.Clean(root) {
+			if !chatForce {
+				return fmt.Errorf("session %s was created for %s, but the current analysis root is %s; use --force to resume anyway", session.ID, session.Root, root)
+			}
+			log.Printf("会话 %s 的根目录 %s 与当前分析结果的根目录 %s 不一致\n", session.ID, session.Root, root)
+			session.Root = root
+		}
+		fmt.Printf("已恢复会话 %s (%d 轮问答, %d 个文件)\n", session.ID, len(session.Turns), len(session.Files))
+	}
+	if streamAnswer {
+		aiClient.SetStreamWriter(os.Stdout)
+	}
+	resolver := code.NewFileResolver(root, known)
+	fmt.Println(chatHelp)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for {
+		fmt.Print("> ")
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "/") {
+			if quit := runChatCommand(session, resolver, line); quit {
+				break
+			}
+			continue
+		}
+
+		summary, err := questionSummary(aiClient, index, line)
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		answer, err := aiClient.AIChat(session, summary, line, code.GenNodeHelpInfo()+code.GenCodeUseDocHelpInfo())
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		if !streamAnswer {
+			fmt.Println(answer.Text)
+		}
+		for _, citation := range answer.Citations {
+			if citation.Invalid != "" {
+				fmt.Printf("无效引用: %q (%s)\n", citation.Quoted(), citation.Invalid)
+			}
+		}
+		if err := session.Save(""); err != nil {
+			log.Printf("Failed to save session: %v\n", err)
+		}
+	}
+	if err := session.Save(""); err != nil {
+		return fmt.Errorf("failed to save session: %v", err)
+	}
+	fmt.Printf("会话已保存: %s, 使用 --session %s 恢复\n", session.Path(), session.ID)
+	return scanner.Err()
+}
+
+// runChatCommand 执行斜杠命令，返回 true 表示退出
+func runChatCommand(session *code.ChatSession, resolver *code.FileResolver, line string) bool {
+	command, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch command {
+	case "/files":
+		if len(session.Files) == 0 && len(session.Opened) == 0 {
+			fmt.Println("还没有文件")
+		}
+		for _, name := range session.FileNames() {
+			fmt.Printf("  %s: %s\n", name, strings.TrimSpace(session.Files[name].Why))
+		}
+		for _, name := range session.Opened {
+			fmt.Printf("  %s (已打开)\n", name)
+		}
+	case "/open":
+		if arg == "" {
+			fmt.Println("用法: /open <path>")
+			break
+		}
+		file, err := resolver.Resolve(arg)
+		if err != nil {
+			fmt.Println("无法打开:", err)
+			break
+		}
+		session.Open(file)
+		fmt.Println("已打开", file)
+	case "/reset":
+		session.Reset()
+		fmt.Println("已清空对话")
+	case "/save":
+		if err := session.Save(arg); err != nil {
+			fmt.Println("保存失败:", err)
+			break
+		}
+		fmt.Println("已保存到", session.Path())
+	case "/help":
+		fmt.Println(chatHelp)
+	case "/exit", "/quit":
+		return true
+	default:
+		fmt.Printf("未知命令 %s, 输入 /help 查看帮助\n", command)
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	code "codetest"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var endpointsFormat string
+
+// endpointsCmd 静态识别项目中注册的 HTTP 路由，不调用 AI
+//
+//	go run entry/main.go endpoints -d ./ --format yaml
+var endpointsCmd = &cobra.Command{
+	Use:   "endpoints",
+	Short: "List HTTP routes registered with net/http, gin, echo, chi or gorilla/mux",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runEndpoints(dir)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(endpointsCmd)
+
+	endpointsCmd.Flags().StringVarP(&dir, "dir", "d", "", "Directory to analyze (required)")
+	endpointsCmd.Flags().StringVarP(&endpointsFormat, "format", "f", "table", "输出格式: table | json | yaml")
+	addWalkFlags(endpointsCmd)
+
+	err := endpointsCmd.MarkFlagRequired("dir")
+	if err != nil {
+		log.Println("Error: dir flag is required", err)
+		return
+	}
+}
+
+// collectParseResults 静态解析目录下的所有文件
+func collectParseResults(directory string, parser *code.Parser) ([]*code.ParseResult, error) {
+	opts, err := newWalkOptions(directory)
+	if err != nil {
+		return nil, err
+	}
+	var results []*code.ParseResult
+	err = code.Walk(directory, opts, func(path string) {
+		result, err := parser.ParseByFile(path)
+		if err != nil {
+			log.Printf("Failed to parse file %s: %v\n", path, err)
+			return
+		}
+		results = append(results, result)
+	})
+	return results, err
+}
+
+// runEndpoints 输出项目中的路由列表
+func runEndpoints(directory string) error {
+	results, err := collectParseResults(directory, code.NewParser())
+	if err != nil {
+		return err
+	}
+	endpoints := code.ResolveEndpointTypes(results)
+
+	switch endpointsFormat {
+	case "json":
+		return printJSON(endpoints)
+	case "yaml":
+		data, err := yaml.Marshal(endpoints)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tPATH\tHANDLER\tREQUEST\tRESPONSE\tROUTER\tFILE")
+	for _, ep := range endpoints {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s:%d\n",
+			ep.Method, ep.Path, ep.Handler, ep.RequestType, ep.ResponseType, ep.Router, ep.File, ep.Line)
+	}
+	return w.Flush()
+}
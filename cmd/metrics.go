@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	code "codetest"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	metricsFormat     string
+	metricsSortBy     string
+	metricsLevel      string
+	metricsOnlyHot    bool
+	metricsThresholds = code.DefaultMetricsThresholds
+)
+
+// metricsCmd 统计函数复杂度指标，不调用 AI
+//
+//	go run entry/main.go metrics -d ./ --sort cognitive --only-hot
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Compute per-function complexity metrics and flag hot spots",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMetrics(dir)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(metricsCmd)
+
+	metricsCmd.Flags().StringVarP(&dir, "dir", "d", "", "Directory to analyze (required)")
+	metricsCmd.Flags().StringVarP(&metricsFormat, "format", "f", "table", "输出格式: table | json")
+	metricsCmd.Flags().StringVar(&metricsSortBy, "sort", "cyclomatic", "排序字段: cyclomatic | cognitive | loc | params | nesting | returns | name")
+	metricsCmd.Flags().StringVar(&metricsLevel, "level", "func", "统计粒度: func | file | package")
+	metricsCmd.Flags().BoolVar(&metricsOnlyHot, "only-hot", false, "只输出超过阈值的热点函数")
+	metricsCmd.Flags().IntVar(&metricsThresholds.Cyclomatic, "max-cyclomatic", metricsThresholds.Cyclomatic, "圈复杂度阈值, 0 表示不检查")
+	metricsCmd.Flags().IntVar(&metricsThresholds.Cognitive, "max-cognitive", metricsThresholds.Cognitive, "认知复杂度阈值, 0 表示不检查")
+	metricsCmd.Flags().IntVar(&metricsThresholds.LOC, "max-loc", metricsThresholds.LOC, "函数行数阈值, 0 表示不检查")
+	metricsCmd.Flags().IntVar(&metricsThresholds.Params, "max-params", metricsThresholds.Params, "参数个数阈值, 0 表示不检查")
+	metricsCmd.Flags().IntVar(&metricsThresholds.Nesting, "max-nesting", metricsThresholds.Nesting, "嵌套深度阈值, 0 表示不检查")
+	addWalkFlags(metricsCmd)
+
+	err := metricsCmd.MarkFlagRequired("dir")
+	if err != nil {
+		log.Println("Error: dir flag is required", err)
+		return
+	}
+}
+
+// runMetrics 遍历目录计算指标并输出
+func runMetrics(directory string) error {
+	switch metricsFormat {
+	case "table", "json":
+	default:
+		return fmt.Errorf("unknown metrics format: %s", metricsFormat)
+	}
+	switch metricsSortBy {
+	case "cyclomatic", "cognitive", "loc", "params", "nesting", "returns", "name":
+	default:
+		return fmt.Errorf("unknown metrics sort field: %s", metricsSortBy)
+	}
+	results, err := collectParseResults(directory, code.NewParser())
+	if err != nil {
+		return err
+	}
+	var funcs []*code.FuncMetrics
+	for _, result := range results {
+		funcs = append(funcs, result.Funcs...)
+	}
+
+	switch metricsLevel {
+	case "file":
+		return printAggregateMetrics(code.AggregateByFile(funcs, metricsThresholds))
+	case "package":
+		return printAggregateMetrics(code.AggregateByPackage(funcs, metricsThresholds))
+	case "func":
+		return printFuncMetrics(funcs)
+	default:
+		return fmt.Errorf("unknown metrics level: %s", metricsLevel)
+	}
+}
+
+// funcMetricsRow 函数指标输出行，附带超出的阈值项
+type funcMetricsRow struct {
+	*code.FuncMetrics
+	Exceeded []string `json:"exceeded,omitempty"`
+}
+
+func printFuncMetrics(funcs []*code.FuncMetrics) error {
+	code.SortFuncMetrics(funcs, metricsSortBy)
+	rows := make([]funcMetricsRow, 0, len(funcs))
+	for _, m := range funcs {
+		exceeded := metricsThresholds.Exceeded(m)
+		if metricsOnlyHot && len(exceeded) == 0 {
+			continue
+		}
+		rows = append(rows, funcMetricsRow{FuncMetrics: m, Exceeded: exceeded})
+	}
+
+	if metricsFormat == "json" {
+		return printJSON(rows)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNC\tFILE\tCYCLO\tCOGN\tLOC\tPARAMS\tNEST\tRET\tHOT")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s:%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n",
+			row.FullName(), row.File, row.Line, row.Cyclomatic, row.Cognitive, row.LOC,
+			row.Params, row.MaxNesting, row.Returns, strings.Join(row.Exceeded, ","))
+	}
+	return w.Flush()
+}
+
+func printAggregateMetrics(aggs []*code.AggregateMetrics) error {
+	if metricsOnlyHot {
+		hot := aggs[:0]
+		for _, agg := range aggs {
+			if agg.Hotspots > 0 {
+				hot = append(hot, agg)
+			}
+		}
+		aggs = hot
+	}
+
+	if metricsFormat == "json" {
+		return printJSON(aggs)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPACKAGE\tFUNCS\tLOC\tMAX_CYCLO\tAVG_CYCLO\tMAX_COGN\tAVG_COGN\tMAX_NEST\tHOTSPOTS")
+	for _, agg := range aggs {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.1f\t%d\t%.1f\t%d\t%d\n",
+			agg.Name, agg.Package, agg.Funcs, agg.LOC, agg.MaxCyclomatic, agg.AvgCyclomatic,
+			agg.MaxCognitive, agg.AvgCognitive, agg.MaxNesting, agg.Hotspots)
+	}
+	return w.Flush()
+}
+
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
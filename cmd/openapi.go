@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	code "codetest"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	openapiOutput  string
+	openapiTitle   string
+	openapiVersion string
+)
+
+// openapiCmd 根据静态识别的路由和结构体生成 OpenAPI 3 文档
+//
+//	go run entry/main.go openapi -d ./ -o openapi.yaml -t sk-xxx
+var openapiCmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Generate an OpenAPI 3 document from discovered HTTP routes",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runOpenAPI(dir, apiToken)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(openapiCmd)
+
+	openapiCmd.Flags().StringVarP(&dir, "dir", "d", "", "Directory to analyze (required)")
+	openapiCmd.Flags().StringVarP(&apiToken, "token", "t", "", "API token, 为空时不生成 AI 接口描述")
+	openapiCmd.Flags().StringVarP(&openapiOutput, "output", "o", "openapi.yaml", "文档输出路径, 以 .json 结尾时输出 JSON")
+	openapiCmd.Flags().StringVar(&openapiTitle, "title", "", "文档标题, 默认使用目录名")
+	openapiCmd.Flags().StringVar(&openapiVersion, "api-version", "1.0.0", "接口版本号")
+	addWalkFlags(openapiCmd)
+
+	err := openapiCmd.MarkFlagRequired("dir")
+	if err != nil {
+		log.Println("Error: dir flag is required", err)
+		return
+	}
+}
+
+// runOpenAPI 生成文档并写入文件
+func runOpenAPI(directory, token string) error {
+	parser := code.NewParserWithOptions(code.ParserOptions{IncludeUnexported: true})
+	results, err := collectParseResults(directory, parser)
+	if err != nil {
+		return err
+	}
+	endpoints := code.ResolveEndpointTypes(results)
+	if len(endpoints) == 0 {
+		return fmt.Errorf("no http routes found in %s", directory)
+	}
+
+	title := openapiTitle
+	if title == "" {
+		absDir, _ := filepath.Abs(directory)
+		title = filepath.Base(absDir)
+	}
+	doc := code.BuildOpenAPI(title, openapiVersion, endpoints, results)
+	for _, warning := range doc.Warnings {
+		log.Println(warning)
+	}
+
+	if token != "" {
+		if err := code.NewChatGPTClient(token).AIDescribeOperations(doc, results); err != nil {
+			log.Printf("AI describe operations failed: %v\n", err)
+		}
+	}
+
+	var data []byte
+	if filepath.Ext(openapiOutput) == ".json" {
+		data, err = json.MarshalIndent(doc, "", "  ")
+	} else {
+		data, err = yaml.Marshal(doc)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(openapiOutput, data, 0644); err != nil {
+		return fmt.Errorf("error writing openapi file: %v", err)
+	}
+	fmt.Printf("Generated %s with %d endpoints\n", openapiOutput, len(endpoints))
+	return nil
+}
@@ -6,9 +6,17 @@ import (
 	"github.com/spf13/cobra"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-var summaryFilePath string
+var (
+	summaryFilePath string
+	withInternal    bool
+	candidateLimit  int
+	answerFormat    string
+	streamAnswer    bool
+)
 
 // questionNodeCmd 定义了 file 节点的命令
 //
@@ -32,6 +40,10 @@ func init() {
 	rootCmd.AddCommand(questionNodeCmd) // 将子命令添加到根命令
 	questionNodeCmd.Flags().StringVarP(&apiToken, "token", "t", "", "API token for AI analysis (required)")
 	questionNodeCmd.Flags().StringVarP(&summaryFilePath, "summary-dir", "s", "./result/all.md", "总结文件输出地方")
+	questionNodeCmd.Flags().StringVarP(&answerFormat, "format", "f", "text", "回答的输出格式: text | markdown | json")
+	questionNodeCmd.Flags().BoolVar(&streamAnswer, "stream", true, "text 格式时边生成边输出最终回答, 正文中的引用在回答结束后才验证, 无效的引用按原文列在末尾")
+	questionNodeCmd.Flags().IntVar(&candidateLimit, "candidates", 30, "使用本地检索索引预选的候选文件数量, 0 表示不预选")
+	questionNodeCmd.Flags().BoolVar(&withInternal, "with-internal", false, "分析文件时附带未导出函数的调用关系、字段读写和返回错误等静态信息")
 
 	err := questionNodeCmd.MarkFlagRequired("token")
 	if err != nil {
@@ -42,20 +54,163 @@ func init() {
 
 // runFileNode 主要逻辑
 func runFileNode(token, question string) error {
-	aiClient := code.NewChatGPTClient(token)
-	summary, err := os.ReadFile(summaryFilePath)
+	switch answerFormat {
+	case "text", "markdown", "json":
+	default:
+		return fmt.Errorf("unknown format: %s", answerFormat)
+	}
+	aiClient, index, err := newQuestionClient(token)
+	if err != nil {
+		return err
+	}
+	summary, err := questionSummary(aiClient, index, question)
 	if err != nil {
-		fmt.Println("os.ReadFile(path) Error:", err)
 		return err
 	}
+	// 只有纯文本格式可以边生成边输出，其他格式需要完整的回答
+	streamed := streamAnswer && answerFormat == "text"
+	if streamed {
+		aiClient.SetStreamWriter(os.Stdout)
+	}
 	// 调用 AI 客户端以获取答案
-	answer, err := aiClient.AIQuestion(string(summary), question, code.GenNodeHelpInfo()+code.GenCodeUseDocHelpInfo())
+	answer, err := aiClient.AIQuestion(summary, question, code.GenNodeHelpInfo()+code.GenCodeUseDocHelpInfo())
 	if err != nil {
 		return fmt.Errorf("error: %v", err)
 	}
 
-	// 打印生成的答案
-	fmt.Println("AI 回复结果：")
-	fmt.Println(answer)
+	// 过程信息输出到日志，标准输出只包含回答
+	switch answerFormat {
+	case "json":
+		return printJSON(answer)
+	case "markdown":
+		fmt.Print(answer.Markdown())
+	default:
+		if streamed {
+			fmt.Print(answer.PlainTextDetails())
+		} else {
+			fmt.Print(answer.PlainText())
+		}
+	}
 	return nil
 }
+
+// newQuestionClient 创建问答使用的客户端，并根据结果索引设置源码根目录和已知的文件，结果索引无法读取时返回的索引为 nil
+func newQuestionClient(token string) (*code.ChatGPTClient, *code.ResultIndex, error) {
+	aiClient := code.NewChatGPTClient(token)
+	if withInternal {
+		aiClient.EnableInternalFacts()
+	}
+	// 总结文件中的路径相对于被分析的目录，根目录记录在同一输出目录的结果索引中
+	index, err := code.LoadResultIndex(filepath.Join(filepath.Dir(summaryFilePath), code.ResultIndexFileName))
+	if err != nil {
+		log.Printf("Failed to load result index: %v\n", err)
+		return aiClient, nil, nil
+	}
+	if index.Root != "" {
+		aiClient.SetSourceRoot(index.Root)
+		aiClient.SetKnownFiles(index.Sources())
+	}
+	return aiClient, index, nil
+}
+
+// questionSummary 问题使用的总结信息：能够缩小候选文件的范围时只包含候选文件的摘要，否则为按相关性排序的完整总结文件
+func questionSummary(aiClient *code.ChatGPTClient, index *code.ResultIndex, question string) (string, error) {
+	if index != nil {
+		if candidates, ok := selectCandidates(aiClient, index, question); ok {
+			return candidates, nil
+		}
+	}
+	summary, err := os.ReadFile(summaryFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read summary file: %v", err)
+	}
+	return code.RankSummary(string(summary), question), nil
+}
+
+// selectCandidates 选择候选文件并返回它们的摘要：
+//  1. 有摘要树时由 AI 根据架构概览和包摘要选择相关的包
+//  2. 有检索索引时在这些包中按 BM25 和向量检索的融合排序选出前 candidateLimit 个文件
+//
+// 两者都不可用或没有选出任何文件时返回 false，使用完整的总结文件
+func selectCandidates(aiClient *code.ChatGPTClient, index *code.ResultIndex, question string) (string, bool) {
+	resultDir := filepath.Dir(summaryFilePath)
+	entries := code.LoadSummaryEntries(resultDir, index, nil)
+	narrowed := false
+
+	tree, err := code.LoadSummaryTree(filepath.Join(resultDir, code.SummaryTreeFileName))
+	if err != nil {
+		log.Printf("Failed to load summary tree: %v\n", err)
+	}
+	if tree != nil && len(tree.Packages) > 0 {
+		packages, err := aiClient.AISelectPackages(tree, question)
+		if err != nil {
+			log.Printf("Failed to select packages: %v\n", err)
+		} else if filtered := code.FilterEntriesByPackage(entries, packages); len(filtered) > 0 {
+			entries, narrowed = filtered, true
+		}
+	}
+
+	if candidateLimit > 0 {
+		if ranked := rankEntries(resultDir, entries, question, aiClient); len(ranked) > 0 {
+			entries, narrowed = ranked, true
+		}
+	}
+	if !narrowed {
+		return "", false
+	}
+
+	var strBuilder strings.Builder
+	if tree != nil && tree.Overview != "" {
+		strBuilder.WriteString("架构概览:\n")
+		strBuilder.WriteString(tree.Overview)
+		strBuilder.WriteString("\n---\n")
+	}
+	for _, entry := range entries {
+		strBuilder.WriteString(entry.Format())
+	}
+	return strBuilder.String(), true
+}
+
+// rankEntries 使用本地检索索引(以及可用时的向量索引)为候选文件排序，返回得分最高的 candidateLimit 个，没有索引或没有命中时返回 nil
+func rankEntries(resultDir string, entries []*code.SummaryEntry, question string, aiClient *code.ChatGPTClient) []*code.SummaryEntry {
+	retriever, err := newRetriever(resultDir, aiClient)
+	if err != nil {
+		log.Printf("Failed to load search index: %v\n", err)
+		return nil
+	}
+	retrieval, err := retriever.Retrieve(question, 0)
+	if err != nil {
+		log.Printf("Semantic search failed, using keyword search only: %v\n", err)
+	}
+	byPath := make(map[string]*code.SummaryEntry, len(entries))
+	for _, entry := range entries {
+		byPath[entry.Path] = entry
+	}
+	// 列出文件中的符号，AI 选择文件时可以同时选择具体的函数
+	symbols := make(map[string][]string)
+	if retriever.Keyword != nil {
+		for _, doc := range retriever.Keyword.Docs {
+			symbols[doc.Path] = doc.Symbols
+		}
+	}
+	hints := make(map[string][]string)
+	for _, symbol := range retrieval.Symbols {
+		hints[symbol.Path] = append(hints[symbol.Path], symbol.Name)
+	}
+	aiClient.SetSymbolHints(hints)
+
+	var ranked []*code.SummaryEntry
+	for _, file := range retrieval.Files {
+		entry, ok := byPath[file]
+		if !ok {
+			continue
+		}
+		log.Printf("候选文件 %s %s\n", file, strings.Join(hints[file], ","))
+		entry.Symbols = symbols[file]
+		ranked = append(ranked, entry)
+		if len(ranked) == candidateLimit {
+			break
+		}
+	}
+	return ranked
+}
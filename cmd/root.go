@@ -1,9 +1,18 @@
 package cmd
 
 import (
+	code "codetest"
+
 	"github.com/spf13/cobra"
 )
 
+var (
+	includeGlobs   []string
+	excludeGlobs   []string
+	verbose        bool
+	followSymlinks bool
+)
+
 // rootCmd 定义了主命令
 var rootCmd = &cobra.Command{
 	Use:   "code-analyzer",
@@ -14,3 +23,30 @@ var rootCmd = &cobra.Command{
 func Execute() error {
 	return rootCmd.Execute()
 }
+
+// addWalkFlags 为需要遍历目录的命令添加文件过滤参数
+func addWalkFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&includeGlobs, "include", nil, "只处理匹配的文件, 相对于分析目录的 doublestar 通配符, 例如 'internal/**/*.go'")
+	cmd.Flags().StringSliceVar(&excludeGlobs, "exclude", nil, "跳过匹配的文件或目录, 相对于分析目录的 doublestar 通配符")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "输出每个被跳过的文件及原因")
+	cmd.Flags().BoolVar(&followSymlinks, "follow-symlinks", false, "进入指向目录的符号链接, 自动跳过循环")
+}
+
+// newFileFilter 根据命令行参数创建文件过滤器
+func newFileFilter(directory string) (*code.FileFilter, error) {
+	filter, err := code.NewFileFilter(directory, includeGlobs, excludeGlobs)
+	if err != nil {
+		return nil, err
+	}
+	filter.Verbose = verbose
+	return filter, nil
+}
+
+// newWalkOptions 根据命令行参数创建遍历选项
+func newWalkOptions(directory string) (code.WalkOptions, error) {
+	filter, err := newFileFilter(directory)
+	if err != nil {
+		return code.WalkOptions{}, err
+	}
+	return code.WalkOptions{Filter: filter, FollowSymlinks: followSymlinks}, nil
+}
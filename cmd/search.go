@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	code "codetest"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var searchLimit int
+
+// searchCmd 在分析结果中检索与查询相关的文件和符号
+//
+//	go run entry/main.go search "how are retries handled" -o ./result -t sk-xxx
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search analyzed files and symbols with keyword and semantic retrieval",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSearch(outputDir, args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "./result", "analyze 命令的输出目录")
+	searchCmd.Flags().StringVarP(&apiToken, "token", "t", "", "API token, 用于生成查询的向量; 为空时只使用关键词检索")
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "k", 10, "输出的文件和符号数量")
+}
+
+func runSearch(resultDir, query string) error {
+	var aiClient *code.ChatGPTClient
+	if apiToken != "" {
+		aiClient = code.NewChatGPTClient(apiToken)
+	}
+	retriever, err := newRetriever(resultDir, aiClient)
+	if err != nil {
+		return err
+	}
+	if retriever.Keyword == nil && retriever.Vectors == nil {
+		return fmt.Errorf("no search index found in %s, run analyze first", resultDir)
+	}
+	retrieval, err := retriever.Retrieve(query, searchLimit)
+	if err != nil {
+		log.Printf("Semantic search failed, using keyword search only: %v\n", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	mode := "keyword"
+	if retrieval.Semantic {
+		mode = "keyword + semantic"
+	}
+	fmt.Fprintf(w, "Files (%s):\n", mode)
+	for i, file := range retrieval.Files {
+		fmt.Fprintf(w, "%d\t%s\n", i+1, file)
+	}
+	if len(retrieval.Symbols) > 0 {
+		fmt.Fprintln(w, "\nSymbols:")
+		for i, symbol := range retrieval.Symbols {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%.3f\n", i+1, symbol.Name, symbol.Path, symbol.Score)
+		}
+	}
+	if len(retrieval.Packages) > 0 {
+		fmt.Fprintln(w, "\nPackages:")
+		for i, pkg := range retrieval.Packages {
+			fmt.Fprintf(w, "%d\t%s\n", i+1, pkg)
+		}
+	}
+	return w.Flush()
+}
+
+// newRetriever 读取输出目录中的关键词索引和向量索引，aiClient 为空或没有向量索引时只使用关键词检索
+func newRetriever(resultDir string, aiClient *code.ChatGPTClient) (*code.Retriever, error) {
+	keyword, err := code.LoadSearchIndex(filepath.Join(resultDir, code.SearchIndexFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load search index: %v", err)
+	}
+	retriever := &code.Retriever{Keyword: keyword}
+	store, err := code.LoadFileVectorStore(filepath.Join(resultDir, code.VectorStoreFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vector store: %v", err)
+	}
+	if store.Len() > 0 && aiClient != nil {
+		retriever.Vectors = store
+		retriever.Embedder = aiClient
+	}
+	return retriever, nil
+}
@@ -6,6 +6,8 @@ import (
 	"go/parser"
 	"go/token"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -13,15 +15,45 @@ import (
 type StructInfo struct {
 	Fields  []string
 	Methods []string
+	// FieldDefs 字段的详细定义，包含 json tag，用于生成接口文档
+	FieldDefs []*StructField
+}
+
+// StructField 结构体字段定义
+type StructField struct {
+	Name      string
+	Type      string
+	JSONName  string
+	OmitEmpty bool
+	Embedded  bool
 }
 
 // ParseResult 解析结果
 type ParseResult struct {
+	FilePath     string
+	Imports      []string
 	Structs      map[string]*StructInfo
 	Interfaces   map[string][]string
 	Constants    []string
 	ExportedFunc []string
 	ExportedVar  []string
+	PackageName  string
+	Funcs        []*FuncMetrics
+	// 以下字段仅在 ParserOptions.IncludeUnexported 开启时填充
+	UnexportedFunc []string
+	UnexportedVar  []string
+	// FuncFacts 记录函数体内的调用、字段读写和返回的错误
+	FuncFacts []*FuncFacts
+	// Endpoints 文件中注册的 HTTP 路由
+	Endpoints []*Endpoint
+	// HandlerIO 函数名 -> 绑定的请求结构体和返回的响应结构体
+	HandlerIO map[string]*HandlerIO
+	// Language 文件所属的语言，见 Language.Name
+	Language string
+	// Declarations 非 Go 语言中无法归入以上字段的顶层声明，例如 SQL 迁移中的 ALTER TABLE
+	Declarations []string
+	// Docs 文件、类型和函数的文档注释
+	Docs []string
 }
 
 // PrintResults 打印解析结果
@@ -78,11 +110,50 @@ func (p *ParseResult) PrintResults() {
 		}
 	}
 
+	if len(p.UnexportedFunc) > 0 {
+		fmt.Println("\nUnexported Functions:")
+		for _, fn := range p.UnexportedFunc {
+			fmt.Printf("- %s\n", fn)
+		}
+	}
+
+	if len(p.UnexportedVar) > 0 {
+		fmt.Println("\nUnexported Variables:")
+		for _, v := range p.UnexportedVar {
+			if len(v) > 64 {
+				v = v[0:64] + "..."
+			}
+			fmt.Printf("- %s\n", v)
+		}
+	}
+
+	if len(p.Endpoints) > 0 {
+		fmt.Println("\nAPI Endpoints:")
+		for _, ep := range p.Endpoints {
+			fmt.Printf("- %s (%s)\n", ep.String(), ep.Router)
+		}
+	}
+
+	if len(p.Funcs) > 0 {
+		fmt.Println("\nFunction Metrics:")
+		for _, m := range p.Funcs {
+			fmt.Printf("- %s: cyclomatic=%d cognitive=%d loc=%d params=%d nesting=%d returns=%d\n",
+				m.FullName(), m.Cyclomatic, m.Cognitive, m.LOC, m.Params, m.MaxNesting, m.Returns)
+		}
+	}
+
+}
+
+// ParserOptions 解析选项
+type ParserOptions struct {
+	// IncludeUnexported 同时记录未导出的函数、方法和变量及其函数体信息
+	IncludeUnexported bool
 }
 
 // Parser 解析器
 type Parser struct {
 	filePath string
+	options  ParserOptions
 }
 
 // NewParser 创建新的解析器
@@ -90,6 +161,11 @@ func NewParser() *Parser {
 	return &Parser{}
 }
 
+// NewParserWithOptions 使用指定选项创建解析器
+func NewParserWithOptions(options ParserOptions) *Parser {
+	return &Parser{options: options}
+}
+
 // ParseByFile Parse 解析源代码文件
 func (p *Parser) ParseByFile(filePath string) (*ParseResult, error) {
 	// 读取文件内容
@@ -107,16 +183,25 @@ func (p *Parser) ParseByFile(filePath string) (*ParseResult, error) {
 		return nil, err
 	}
 	result := ParseResult{
+		FilePath:     filePath,
+		Language:     LanguageGo,
 		Structs:      make(map[string]*StructInfo),
 		Interfaces:   make(map[string][]string),
 		Constants:    []string{},
 		ExportedFunc: []string{},
 		ExportedVar:  []string{},
+		PackageName:  f.Name.Name,
+	}
+	if f.Doc != nil {
+		result.Docs = append(result.Docs, strings.TrimSpace(f.Doc.Text()))
 	}
 	// 遍历 AST 树
 	ast.Inspect(f, func(n ast.Node) bool {
 		switch t := n.(type) {
 		case *ast.GenDecl:
+			if t.Doc != nil {
+				result.Docs = append(result.Docs, strings.TrimSpace(t.Doc.Text()))
+			}
 			// 解析常量和变量声明
 			if t.Tok == token.CONST {
 				result.Constants = append(result.Constants, parseGenDecl(t)...)
@@ -125,6 +210,10 @@ func (p *Parser) ParseByFile(filePath string) (*ParseResult, error) {
 			}
 
 		case *ast.TypeSpec:
+			// 分组声明中类型的文档注释
+			if t.Doc != nil {
+				result.Docs = append(result.Docs, strings.TrimSpace(t.Doc.Text()))
+			}
 			// 解析结构体或接口
 			if structType, ok := t.Type.(*ast.StructType); ok {
 				parseStruct(t, structType, result.Structs)
@@ -133,12 +222,42 @@ func (p *Parser) ParseByFile(filePath string) (*ParseResult, error) {
 			}
 
 		case *ast.FuncDecl:
-			// 解析导出函数或方法
-			parseFunc(t, result.Structs, &result.ExportedFunc)
+			if t.Doc != nil {
+				result.Docs = append(result.Docs, strings.TrimSpace(t.Doc.Text()))
+			}
+			// 解析导出函数或方法，开启选项时同时解析未导出的函数
+			exported := ast.IsExported(t.Name.Name)
+			if exported {
+				parseFunc(t, result.Structs, &result.ExportedFunc)
+			} else if p.options.IncludeUnexported {
+				parseFunc(t, result.Structs, &result.UnexportedFunc)
+			}
+			if exported || p.options.IncludeUnexported {
+				result.FuncFacts = append(result.FuncFacts, collectFuncFacts(t))
+			}
+			// 计算函数复杂度指标
+			result.Funcs = append(result.Funcs, computeFuncMetrics(fset, filePath, result.PackageName, t))
 		}
 		return true
 	})
 
+	// 未导出变量只记录包级别的声明，函数体内的局部变量不属于文件的结构信息
+	if p.options.IncludeUnexported {
+		for _, decl := range f.Decls {
+			if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.VAR {
+				result.UnexportedVar = append(result.UnexportedVar, parseUnexportedVars(genDecl)...)
+			}
+		}
+	}
+
+	for _, imp := range f.Imports {
+		result.Imports = append(result.Imports, strings.Trim(imp.Path.Value, `"`))
+	}
+
+	// 识别路由注册
+	result.Endpoints = extractEndpoints(fset, filePath, f)
+	result.HandlerIO = extractHandlerIO(f)
+
 	return &result, nil
 }
 
@@ -152,10 +271,50 @@ func parseStruct(t *ast.TypeSpec, structType *ast.StructType, structs map[string
 
 	for _, field := range structType.Fields.List {
 		fieldType := exprToString(field.Type)
+		jsonName, omitEmpty := parseJSONTag(field.Tag)
 		for _, name := range field.Names {
 			structs[structName].Fields = append(structs[structName].Fields, fmt.Sprintf("%s: %s", name.Name, fieldType))
+			structs[structName].FieldDefs = append(structs[structName].FieldDefs, &StructField{
+				Name:      name.Name,
+				Type:      fieldType,
+				JSONName:  jsonName,
+				OmitEmpty: omitEmpty,
+			})
+		}
+		if len(field.Names) == 0 {
+			// 匿名嵌入字段
+			structs[structName].FieldDefs = append(structs[structName].FieldDefs, &StructField{
+				Name:      strings.TrimPrefix(fieldType, "*"),
+				Type:      fieldType,
+				JSONName:  jsonName,
+				OmitEmpty: omitEmpty,
+				Embedded:  true,
+			})
+		}
+	}
+}
+
+// 解析字段的 json tag，返回字段名和是否 omitempty
+func parseJSONTag(tag *ast.BasicLit) (string, bool) {
+	if tag == nil {
+		return "", false
+	}
+	value, err := strconv.Unquote(tag.Value)
+	if err != nil {
+		return "", false
+	}
+	jsonTag := reflect.StructTag(value).Get("json")
+	if jsonTag == "" {
+		return "", false
+	}
+	parts := strings.Split(jsonTag, ",")
+	omitEmpty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
 		}
 	}
+	return parts[0], omitEmpty
 }
 
 // 解析接口并存储方法
@@ -172,22 +331,20 @@ func parseInterface(t *ast.TypeSpec, interfaceType *ast.InterfaceType, interface
 	}
 }
 
-// 解析导出函数和方法
-func parseFunc(t *ast.FuncDecl, structs map[string]*StructInfo, exportedFuncs *[]string) {
-	if ast.IsExported(t.Name.Name) {
-		params := getParamString(t.Type.Params)
-		results := getParamString(t.Type.Results)
-
-		if t.Recv != nil {
-			// 解析方法的接收者
-			receiverType := exprToString(t.Recv.List[0].Type)
-			if structInfo, ok := structs[receiverType]; ok {
-				structInfo.Methods = append(structInfo.Methods, fmt.Sprintf("%s(%s) (%s)", t.Name.Name, params, results))
-			}
-		} else {
-			// 普通导出函数
-			*exportedFuncs = append(*exportedFuncs, fmt.Sprintf("%s(%s) (%s)", t.Name.Name, params, results))
+// 解析函数和方法，方法记录到对应结构体，普通函数记录到 funcs
+func parseFunc(t *ast.FuncDecl, structs map[string]*StructInfo, funcs *[]string) {
+	params := getParamString(t.Type.Params)
+	results := getParamString(t.Type.Results)
+
+	if t.Recv != nil {
+		// 解析方法的接收者
+		receiverType := exprToString(t.Recv.List[0].Type)
+		if structInfo, ok := structs[receiverType]; ok {
+			structInfo.Methods = append(structInfo.Methods, fmt.Sprintf("%s(%s) (%s)", t.Name.Name, params, results))
 		}
+	} else {
+		// 普通函数
+		*funcs = append(*funcs, fmt.Sprintf("%s(%s) (%s)", t.Name.Name, params, results))
 	}
 }
 
@@ -208,22 +365,31 @@ func parseGenDecl(genDecl *ast.GenDecl) []string {
 
 // 解析导出变量
 func parseExportedVars(genDecl *ast.GenDecl) []string {
-	var exportedVars []string
+	return parseVars(genDecl, true)
+}
+
+// 解析未导出变量
+func parseUnexportedVars(genDecl *ast.GenDecl) []string {
+	return parseVars(genDecl, false)
+}
+
+func parseVars(genDecl *ast.GenDecl, exported bool) []string {
+	var vars []string
 	for _, spec := range genDecl.Specs {
 		if valueSpec, ok := spec.(*ast.ValueSpec); ok {
 			for _, name := range valueSpec.Names {
-				if ast.IsExported(name.Name) {
+				if name.Name != "_" && ast.IsExported(name.Name) == exported {
 					val := ""
 					if len(valueSpec.Values) > 0 {
 						val = exprToString(valueSpec.Values[0])
 					}
 
-					exportedVars = append(exportedVars, fmt.Sprintf("%s = %s", name.Name, val))
+					vars = append(vars, fmt.Sprintf("%s = %s", name.Name, val))
 				}
 			}
 		}
 	}
-	return exportedVars
+	return vars
 }
 
 // 获取参数字符串
@@ -0,0 +1,105 @@
+package code
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// embeddingBatchSize 每次请求向量化的文本数量
+const embeddingBatchSize = 64
+
+// EmbeddingProvider 文本向量化服务
+type EmbeddingProvider interface {
+	Embed(texts []string) ([][]float32, error)
+}
+
+// BuildVectorItems 生成需要向量化的文件、包和符号条目，tree 和 symbols 可以为空
+func BuildVectorItems(entries []*SummaryEntry, tree *SummaryTree, symbols map[string]*ParseResult) []*VectorItem {
+	var items []*VectorItem
+	add := func(kind, path, name, text string) {
+		id := kind + ":" + path
+		if kind == VectorKindPackage {
+			id = kind + ":" + name
+		} else if name != "" {
+			id += "#" + name
+		}
+		items = append(items, &VectorItem{ID: id, Kind: kind, Path: path, Name: name, Hash: summaryHash(text), text: text})
+	}
+
+	for _, entry := range entries {
+		add(VectorKindFile, entry.Path, "", FormatSummaryEntry(entry.Path, &entry.Result))
+
+		result := symbols[entry.Path]
+		if result == nil {
+			continue
+		}
+		description := shortText(entry.Result.FunctionDescription, 200)
+		for _, symbol := range symbolSignatures(result) {
+			add(VectorKindSymbol, entry.Path, symbol[0], fmt.Sprintf("%s\n文件: %s\n文件功能: %s\n", symbol[1], entry.Path, description))
+		}
+	}
+	if tree != nil {
+		for _, pkg := range tree.Packages {
+			add(VectorKindPackage, "", pkg.Package, fmt.Sprintf("包: %s\n摘要: %s\n文件: %s\n", pkg.Package, pkg.Description, strings.Join(pkg.Files, ", ")))
+		}
+	}
+	return items
+}
+
+// symbolSignatures 返回文件中的类型和函数及其签名，方法使用 Type.Method 的形式
+func symbolSignatures(result *ParseResult) [][2]string {
+	var symbols [][2]string
+	for name, info := range result.Structs {
+		symbols = append(symbols, [2]string{name, fmt.Sprintf("type %s struct { %s }", name, strings.Join(info.Fields, "; "))})
+		for _, method := range info.Methods {
+			methodName, _, _ := strings.Cut(method, "(")
+			symbols = append(symbols, [2]string{name + "." + methodName, fmt.Sprintf("func (%s) %s", name, method)})
+		}
+	}
+	for name, methods := range result.Interfaces {
+		symbols = append(symbols, [2]string{name, fmt.Sprintf("type %s interface { %s }", name, strings.Join(methods, "; "))})
+	}
+	for _, fn := range append(append([]string{}, result.ExportedFunc...), result.UnexportedFunc...) {
+		name, _, _ := strings.Cut(fn, "(")
+		symbols = append(symbols, [2]string{name, "func " + fn})
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i][0] < symbols[j][0]
+	})
+	return symbols
+}
+
+// EmbedItems 为文本发生变化的条目生成向量并写入存储，删除存储中不在 items 里的条目，返回新生成的向量数量
+func EmbedItems(store VectorStore, items []*VectorItem, provider EmbeddingProvider) (int, error) {
+	ids := make(map[string]bool, len(items))
+	var pending []*VectorItem
+	for _, item := range items {
+		ids[item.ID] = true
+		if old := store.Get(item.ID); old != nil && old.Hash == item.Hash && len(old.Vector) > 0 {
+			continue
+		}
+		pending = append(pending, item)
+	}
+	store.Retain(ids)
+
+	for start := 0; start < len(pending); start += embeddingBatchSize {
+		batch := pending[start:min(start+embeddingBatchSize, len(pending))]
+		texts := make([]string, len(batch))
+		for i, item := range batch {
+			texts[i] = item.text
+		}
+		vectors, err := provider.Embed(texts)
+		if err != nil {
+			return start, err
+		}
+		if len(vectors) != len(batch) {
+			return start, fmt.Errorf("embedding provider returned %d vectors for %d texts", len(vectors), len(batch))
+		}
+		for i, item := range batch {
+			item.Vector = vectors[i]
+			store.Upsert(item)
+		}
+	}
+	return len(pending), nil
+}
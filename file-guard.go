@@ -0,0 +1,158 @@
+package code
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// 超出大小限制的文件的处理策略
+const (
+	// OversizedPolicySkip 直接跳过
+	OversizedPolicySkip = "skip"
+	// OversizedPolicyChunk 按顶层声明切分为多段分别分析后合并
+	OversizedPolicyChunk = "chunk"
+	// OversizedPolicyStatic 使用静态模板生成摘要，不调用 AI
+	OversizedPolicyStatic = "static"
+)
+
+// FileLimits 发送给 AI 的文件大小限制，值为 0 表示不限制
+type FileLimits struct {
+	MaxBytes int
+	MaxLines int
+}
+
+// FileCheck 文件内容检查结果
+type FileCheck struct {
+	Bytes int
+	Lines int
+	// Binary 包含 NUL 字节或不是合法的 UTF-8
+	Binary    bool
+	Oversized bool
+	Reason    string
+}
+
+// CheckFileContent 检查文件内容是否为二进制或超出大小限制
+func CheckFileContent(content []byte, limits FileLimits) FileCheck {
+	check := FileCheck{Bytes: len(content), Lines: bytes.Count(content, []byte("\n"))}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		check.Lines++
+	}
+
+	switch {
+	case bytes.IndexByte(content, 0) >= 0:
+		check.Binary = true
+		check.Reason = "binary content (NUL byte)"
+	case !utf8.Valid(content):
+		check.Binary = true
+		check.Reason = "invalid UTF-8"
+	case limits.MaxBytes > 0 && check.Bytes > limits.MaxBytes:
+		check.Oversized = true
+		check.Reason = fmt.Sprintf("%d bytes exceeds limit %d", check.Bytes, limits.MaxBytes)
+	case limits.MaxLines > 0 && check.Lines > limits.MaxLines:
+		check.Oversized = true
+		check.Reason = fmt.Sprintf("%d lines exceeds limit %d", check.Lines, limits.MaxLines)
+	}
+	return check
+}
+
+// OversizedDescription 超大文件静态摘要中的描述
+func OversizedDescription(check FileCheck, result *ParseResult) string {
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString(fmt.Sprintf("文件过大(%d 字节, %d 行)，静态摘要，未经 AI 分析。", check.Bytes, check.Lines))
+	if result != nil {
+		writeDeclNames(&strBuilder, result)
+	}
+	return strBuilder.String()
+}
+
+// SplitChunks 把文件切分为不超过限制的多段，尽量在顶层声明之间切分
+//
+// 单个顶层声明超过限制时在行边界强制切分
+func SplitChunks(content string, limits FileLimits) []string {
+	lines := strings.SplitAfter(content, "\n")
+	var chunks []string
+	start, size, boundary := 0, 0, -1
+	for i, line := range lines {
+		// 文档注释和其后的声明不切开
+		if i > start && isTopLevelBoundary(line) && !strings.HasPrefix(lines[i-1], "//") {
+			boundary = i
+		}
+		size += len(line)
+		overBytes := limits.MaxBytes > 0 && size > limits.MaxBytes
+		overLines := limits.MaxLines > 0 && i-start+1 > limits.MaxLines
+		if (overBytes || overLines) && i > start {
+			end := i
+			if boundary > start {
+				end = boundary
+			}
+			chunks = append(chunks, strings.Join(lines[start:end], ""))
+			start, boundary = end, -1
+			size = 0
+			for _, l := range lines[start : i+1] {
+				size += len(l)
+			}
+		}
+	}
+	if rest := strings.Join(lines[start:], ""); rest != "" {
+		chunks = append(chunks, rest)
+	}
+	return chunks
+}
+
+// isTopLevelBoundary 判断该行是否为顶层声明或其文档注释的开始
+func isTopLevelBoundary(line string) bool {
+	for _, prefix := range []string{"func ", "type ", "var ", "const ", "//"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeChunkResults 合并分段分析的结果，原始 YAML 按段保存为多文档
+func MergeChunkResults(raws []string, parsed []ParsedYAML) (string, ParsedYAML) {
+	var merged ParsedYAML
+	var descriptions []string
+	seenImports := make(map[string]bool)
+	for i, p := range parsed {
+		if i == 0 {
+			merged = p
+			merged.FileInfo.Imports = nil
+		} else {
+			merged.StaticEndpoints = append(merged.StaticEndpoints, p.StaticEndpoints...)
+			merged.TestedBehaviors = append(merged.TestedBehaviors, p.TestedBehaviors...)
+			merged.Fixtures = append(merged.Fixtures, p.Fixtures...)
+		}
+		// 静态信息追加在最后一段之后
+		if p.Language != "" {
+			merged.Language = p.Language
+		}
+		if p.ModulePath != "" {
+			merged.ModulePath, merged.ImportPath = p.ModulePath, p.ImportPath
+		}
+		for test, targets := range p.TestTargets {
+			if merged.TestTargets == nil {
+				merged.TestTargets = make(map[string][]string)
+			}
+			merged.TestTargets[test] = targets
+		}
+		if desc := strings.TrimSpace(p.FunctionDescription); desc != "" {
+			descriptions = append(descriptions, desc)
+		}
+		for _, imp := range p.FileInfo.Imports {
+			if !seenImports[imp] {
+				seenImports[imp] = true
+				merged.FileInfo.Imports = append(merged.FileInfo.Imports, imp)
+			}
+		}
+	}
+	merged.FunctionDescription = strings.Join(descriptions, "\n")
+
+	docs := make([]string, 0, len(raws))
+	for _, raw := range raws {
+		docs = append(docs, strings.TrimRight(raw, "\n")+"\n")
+	}
+	return strings.Join(docs, "---\n"), merged
+}
@@ -0,0 +1,54 @@
+package code
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckFileContent(t *testing.T) {
+	limits := FileLimits{MaxBytes: 100, MaxLines: 3}
+	cases := []struct {
+		content   string
+		binary    bool
+		oversized bool
+	}{
+		{"package a\n", false, false},
+		{"package a\x00", true, false},
+		{"package a\xff\xfe", true, false},
+		{"a\nb\nc\nd", false, true},
+		{strings.Repeat("x", 101), false, true},
+	}
+	for _, c := range cases {
+		check := CheckFileContent([]byte(c.content), limits)
+		if check.Binary != c.binary || check.Oversized != c.oversized {
+			t.Errorf("CheckFileContent(%q) = %+v, want binary=%v oversized=%v", c.content, check, c.binary, c.oversized)
+		}
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	src := `package a
+
+// A 文档注释
+func A() {
+	x := 1
+	_ = x
+}
+
+// B 文档注释
+func B() {
+	y := 2
+	_ = y
+}
+`
+	chunks := SplitChunks(src, FileLimits{MaxLines: 8})
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks: %q", len(chunks), chunks)
+	}
+	if !strings.HasPrefix(chunks[1], "// B 文档注释\nfunc B()") {
+		t.Errorf("second chunk should start at the doc comment of B, got %q", chunks[1])
+	}
+	if strings.Join(chunks, "") != src {
+		t.Error("chunks do not reassemble to the original content")
+	}
+}
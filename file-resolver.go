@@ -0,0 +1,153 @@
+package code
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileResolver 把 AI 给出的文件路径解析为分析结果中已知的文件，拒绝分析目录以外的路径
+type FileResolver struct {
+	// root 被分析目录的绝对路径，为空时使用当前目录
+	root string
+	// known 结果索引中的源文件，为空时不限制文件，只检查路径是否在根目录下
+	known []string
+	set   map[string]bool
+}
+
+// NewFileResolver 创建文件路径解析器，known 为相对于 root、使用 / 分隔的路径
+func NewFileResolver(root string, known []string) *FileResolver {
+	r := &FileResolver{root: root, set: make(map[string]bool, len(known))}
+	for _, file := range known {
+		file = filepath.ToSlash(file)
+		if !r.set[file] {
+			r.set[file] = true
+			r.known = append(r.known, file)
+		}
+	}
+	sort.Strings(r.known)
+	return r
+}
+
+// Resolve 返回相对于根目录的已知文件路径，依次尝试：完全匹配、后缀匹配、文件名匹配和编辑距离最近的路径
+func (r *FileResolver) Resolve(name string) (string, error) {
+	cleaned := strings.Trim(strings.TrimSpace(name), "`'\"<>")
+	if cleaned == "" {
+		return "", fmt.Errorf("empty file path")
+	}
+	if filepath.IsAbs(cleaned) {
+		if r.root == "" {
+			return "", fmt.Errorf("absolute path %s is not allowed", name)
+		}
+		rel, err := filepath.Rel(r.root, cleaned)
+		if err != nil {
+			return "", fmt.Errorf("path %s is outside %s", name, r.root)
+		}
+		cleaned = rel
+	}
+	cleaned = path.Clean(filepath.ToSlash(cleaned))
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") || path.IsAbs(cleaned) {
+		return "", fmt.Errorf("path %s is outside the analyzed directory", name)
+	}
+	if len(r.known) == 0 || r.set[cleaned] {
+		return cleaned, nil
+	}
+
+	// AI 省略了目录前缀，例如 service.go -> internal/user/service.go
+	if match, ok := r.unique(func(file string) bool { return strings.HasSuffix(file, "/"+cleaned) }); ok {
+		return match, nil
+	}
+	if match, ok := r.unique(func(file string) bool { return path.Base(file) == path.Base(cleaned) }); ok {
+		return match, nil
+	}
+	if match, ok := r.closest(cleaned); ok {
+		return match, nil
+	}
+	return "", fmt.Errorf("file %s is not in the analysis results", name)
+}
+
+// Known 不使用编辑距离，只通过完全匹配、后缀匹配或文件名匹配解析为已知文件，没有已知文件时总是返回 false
+func (r *FileResolver) Known(name string) (string, bool) {
+	cleaned := path.Clean(filepath.ToSlash(strings.Trim(strings.TrimSpace(name), "`'\"<>")))
+	if len(r.known) == 0 {
+		return "", false
+	}
+	if r.set[cleaned] {
+		return cleaned, true
+	}
+	if match, ok := r.unique(func(file string) bool { return strings.HasSuffix(file, "/"+cleaned) }); ok {
+		return match, true
+	}
+	return r.unique(func(file string) bool { return path.Base(file) == path.Base(cleaned) })
+}
+
+// HasExtension 文件的扩展名是否与某个已知文件相同，没有已知文件时只要有扩展名就返回 true
+func (r *FileResolver) HasExtension(name string) bool {
+	ext := path.Ext(name)
+	if ext == "" || len(r.known) == 0 {
+		return ext != ""
+	}
+	for _, file := range r.known {
+		if path.Ext(file) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// unique 返回唯一满足条件的文件
+func (r *FileResolver) unique(match func(file string) bool) (string, bool) {
+	found := ""
+	for _, file := range r.known {
+		if match(file) {
+			if found != "" {
+				return "", false
+			}
+			found = file
+		}
+	}
+	return found, found != ""
+}
+
+// closest 返回编辑距离最小的文件，距离超过路径长度的五分之一(至少 2)或有多个最近的文件时视为没有找到
+func (r *FileResolver) closest(name string) (string, bool) {
+	maxDistance := len(name) / 5
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	best, bestDistance, ties := "", maxDistance+1, 0
+	for _, file := range r.known {
+		distance := editDistance(name, file)
+		switch {
+		case distance < bestDistance:
+			best, bestDistance, ties = file, distance, 1
+		case distance == bestDistance:
+			ties++
+		}
+	}
+	return best, best != "" && ties == 1
+}
+
+// editDistance 两个字符串的 Levenshtein 距离
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,47 @@
+package code
+
+import "testing"
+
+func TestFileResolver(t *testing.T) {
+	resolver := NewFileResolver("/src/app", []string{
+		"cmd/main.go",
+		"internal/user/service.go",
+		"internal/order/service.go",
+		"internal/order/handler.go",
+		"pkg/retry/retry.go",
+	})
+	cases := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"internal/user/service.go", "internal/user/service.go", true},
+		{"./cmd/main.go", "cmd/main.go", true},
+		{"/src/app/pkg/retry/retry.go", "pkg/retry/retry.go", true},
+		{"user/service.go", "internal/user/service.go", true},
+		{"handler.go", "internal/order/handler.go", true},
+		{"internal/order/handlers.go", "internal/order/handler.go", true},
+		{"pkg/retry/retyr.go", "pkg/retry/retry.go", true},
+		// 多个同名文件无法确定
+		{"service.go", "", false},
+		{"/etc/passwd", "", false},
+		{"../../etc/passwd", "", false},
+		{"internal/../../secret.go", "", false},
+		{"internal/payment/gateway.go", "", false},
+	}
+	for _, c := range cases {
+		got, err := resolver.Resolve(c.name)
+		if (err == nil) != c.ok || got != c.want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q, ok=%v", c.name, got, err, c.want, c.ok)
+		}
+	}
+
+	// 没有结果索引时只检查路径是否在根目录下
+	open := NewFileResolver("", nil)
+	if got, err := open.Resolve("a/b.go"); err != nil || got != "a/b.go" {
+		t.Errorf("Resolve without manifest = %q, %v", got, err)
+	}
+	if _, err := open.Resolve("/etc/passwd"); err == nil {
+		t.Error("expected absolute path to be rejected without a root")
+	}
+}
@@ -0,0 +1,186 @@
+package code
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// maxFactExprLen 记录错误表达式时的最大长度
+const maxFactExprLen = 80
+
+// FuncFacts 函数体的静态信息，供问答流程推理内部逻辑
+type FuncFacts struct {
+	Name        string   `json:"name" yaml:"name"`
+	Calls       []string `json:"calls,omitempty" yaml:"calls,omitempty"`
+	FieldReads  []string `json:"field_reads,omitempty" yaml:"field_reads,omitempty"`
+	FieldWrites []string `json:"field_writes,omitempty" yaml:"field_writes,omitempty"`
+	Errors      []string `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// FormatFuncFacts 将函数静态信息格式化为提示词中使用的文本
+func (p *ParseResult) FormatFuncFacts() string {
+	if len(p.FuncFacts) == 0 {
+		return ""
+	}
+	strBuilder := strings.Builder{}
+	for _, facts := range p.FuncFacts {
+		strBuilder.WriteString("- ")
+		strBuilder.WriteString(facts.Name)
+		strBuilder.WriteString("\n")
+		writeFactLine(&strBuilder, "calls", facts.Calls)
+		writeFactLine(&strBuilder, "reads", facts.FieldReads)
+		writeFactLine(&strBuilder, "writes", facts.FieldWrites)
+		writeFactLine(&strBuilder, "errors", facts.Errors)
+	}
+	return strBuilder.String()
+}
+
+func writeFactLine(strBuilder *strings.Builder, label string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	strBuilder.WriteString("  ")
+	strBuilder.WriteString(label)
+	strBuilder.WriteString(": ")
+	strBuilder.WriteString(strings.Join(values, ", "))
+	strBuilder.WriteString("\n")
+}
+
+// funcDeclName 返回带接收者类型的函数名，例如 (*Parser).ParseByFile
+func funcDeclName(fn *ast.FuncDecl) string {
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		return "(" + exprToString(fn.Recv.List[0].Type) + ")." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// collectFuncFacts 收集函数调用、接收者/参数字段的读写以及返回的错误
+func collectFuncFacts(fn *ast.FuncDecl) *FuncFacts {
+	facts := &FuncFacts{Name: funcDeclName(fn)}
+	if fn.Body == nil {
+		return facts
+	}
+
+	// 接收者和参数名 -> 类型名，只跟踪这些变量上的字段访问
+	owners := make(map[string]string)
+	addOwners := func(fields *ast.FieldList) {
+		if fields == nil {
+			return
+		}
+		for _, field := range fields.List {
+			typeName := strings.TrimPrefix(exprToString(field.Type), "*")
+			for _, name := range field.Names {
+				owners[name.Name] = typeName
+			}
+		}
+	}
+	addOwners(fn.Recv)
+	addOwners(fn.Type.Params)
+
+	returnsError := false
+	if results := fn.Type.Results; results != nil && len(results.List) > 0 {
+		returnsError = exprToString(results.List[len(results.List)-1].Type) == "error"
+	}
+
+	seen := make(map[string]bool)
+	add := func(list *[]string, kind, value string) {
+		if seen[kind+value] {
+			return
+		}
+		seen[kind+value] = true
+		*list = append(*list, value)
+	}
+	fieldOf := func(expr ast.Expr) (string, bool) {
+		sel, ok := expr.(*ast.SelectorExpr)
+		if !ok {
+			return "", false
+		}
+		// 取最内层的 x.Field，例如 c.client.Do 记录为 Type.client
+		for {
+			inner, ok := sel.X.(*ast.SelectorExpr)
+			if !ok {
+				break
+			}
+			sel = inner
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return "", false
+		}
+		typeName, ok := owners[ident.Name]
+		if !ok {
+			return "", false
+		}
+		return typeName + "." + sel.Sel.Name, true
+	}
+
+	// 写入的字段先记录下来，读取时跳过赋值左侧
+	written := make(map[ast.Expr]bool)
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range t.Lhs {
+				if field, ok := fieldOf(lhs); ok {
+					add(&facts.FieldWrites, "w", field)
+					written[lhs] = true
+				}
+			}
+		case *ast.IncDecStmt:
+			if field, ok := fieldOf(t.X); ok {
+				add(&facts.FieldWrites, "w", field)
+				written[t.X] = true
+			}
+		case *ast.CallExpr:
+			if name := callName(t.Fun); name != "" {
+				add(&facts.Calls, "c", name)
+			}
+			// 方法调用 x.Method() 不算字段读取，但 x.field.Method() 算
+			if sel, ok := t.Fun.(*ast.SelectorExpr); ok {
+				if _, isIdent := sel.X.(*ast.Ident); isIdent {
+					written[sel] = true
+				}
+			}
+		case *ast.SelectorExpr:
+			if !written[t] {
+				if field, ok := fieldOf(t); ok {
+					add(&facts.FieldReads, "r", field)
+				}
+			}
+		case *ast.ReturnStmt:
+			if returnsError && len(t.Results) > 0 {
+				last := t.Results[len(t.Results)-1]
+				if ident, ok := last.(*ast.Ident); !ok || ident.Name != "nil" {
+					add(&facts.Errors, "e", shortExpr(last))
+				}
+			}
+		}
+		return true
+	})
+	return facts
+}
+
+// callName 返回被调用函数的名称，例如 fmt.Errorf、c.getChatGPTResponse
+func callName(fun ast.Expr) string {
+	switch t := fun.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return shortExpr(t)
+	case *ast.IndexExpr:
+		return callName(t.X)
+	case *ast.ParenExpr:
+		return callName(t.X)
+	default:
+		return ""
+	}
+}
+
+// shortExpr 表达式转字符串并截断
+func shortExpr(expr ast.Expr) string {
+	s := types.ExprString(expr)
+	if len(s) > maxFactExprLen {
+		s = s[:maxFactExprLen] + "..."
+	}
+	return s
+}
@@ -0,0 +1,76 @@
+package code
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseWithUnexportedFacts(t *testing.T) {
+	src := `package demo
+
+import "errors"
+
+var ErrEmpty = errors.New("empty")
+
+var cache = map[string]int{}
+
+type store struct {
+	items []string
+	count int
+}
+
+func (s *store) add(item string) error {
+	if item == "" {
+		return ErrEmpty
+	}
+	var local = 2
+	_ = local
+	s.items = append(s.items, item)
+	s.count++
+	s.log(item)
+	return nil
+}
+
+func (s *store) log(item string) {}
+`
+	path := filepath.Join(t.TempDir(), "store.go")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewParser().ParseByFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.UnexportedVar) != 0 || len(result.FuncFacts) != 0 {
+		t.Errorf("unexported declarations recorded without option: %v %v", result.UnexportedVar, result.FuncFacts)
+	}
+
+	result, err = NewParserWithOptions(ParserOptions{IncludeUnexported: true}).ParseByFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 函数体内的局部变量不记录
+	if want := []string{"cache = unknown"}; !reflect.DeepEqual(result.UnexportedVar, want) {
+		t.Errorf("unexported vars = %v, want %v", result.UnexportedVar, want)
+	}
+
+	facts := result.FuncFacts[0]
+	if facts.Name != "(*store).add" {
+		t.Errorf("name = %s", facts.Name)
+	}
+	if want := []string{"append", "s.log"}; !reflect.DeepEqual(facts.Calls, want) {
+		t.Errorf("calls = %v, want %v", facts.Calls, want)
+	}
+	if want := []string{"store.items", "store.count"}; !reflect.DeepEqual(facts.FieldWrites, want) {
+		t.Errorf("writes = %v, want %v", facts.FieldWrites, want)
+	}
+	if want := []string{"store.items"}; !reflect.DeepEqual(facts.FieldReads, want) {
+		t.Errorf("reads = %v, want %v", facts.FieldReads, want)
+	}
+	if want := []string{"ErrEmpty"}; !reflect.DeepEqual(facts.Errors, want) {
+		t.Errorf("errors = %v, want %v", facts.Errors, want)
+	}
+}
@@ -0,0 +1,326 @@
+package code
+
+import (
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"sort"
+)
+
+// FuncMetrics 单个函数的复杂度指标
+type FuncMetrics struct {
+	Name       string `json:"name" yaml:"name"`
+	Receiver   string `json:"receiver,omitempty" yaml:"receiver,omitempty"`
+	File       string `json:"file" yaml:"file"`
+	Package    string `json:"package" yaml:"package"`
+	Line       int    `json:"line" yaml:"line"`
+	Cyclomatic int    `json:"cyclomatic" yaml:"cyclomatic"`
+	Cognitive  int    `json:"cognitive" yaml:"cognitive"`
+	LOC        int    `json:"loc" yaml:"loc"`
+	Params     int    `json:"params" yaml:"params"`
+	MaxNesting int    `json:"max_nesting" yaml:"max_nesting"`
+	Returns    int    `json:"returns" yaml:"returns"`
+}
+
+// FullName 返回带接收者的函数名，例如 (*Parser).ParseByFile
+func (m *FuncMetrics) FullName() string {
+	if m.Receiver == "" {
+		return m.Name
+	}
+	return "(" + m.Receiver + ")." + m.Name
+}
+
+// AggregateMetrics 文件或包级别的汇总指标
+type AggregateMetrics struct {
+	Name          string  `json:"name" yaml:"name"`
+	Package       string  `json:"package" yaml:"package"`
+	Funcs         int     `json:"funcs" yaml:"funcs"`
+	LOC           int     `json:"loc" yaml:"loc"`
+	MaxCyclomatic int     `json:"max_cyclomatic" yaml:"max_cyclomatic"`
+	AvgCyclomatic float64 `json:"avg_cyclomatic" yaml:"avg_cyclomatic"`
+	MaxCognitive  int     `json:"max_cognitive" yaml:"max_cognitive"`
+	AvgCognitive  float64 `json:"avg_cognitive" yaml:"avg_cognitive"`
+	MaxNesting    int     `json:"max_nesting" yaml:"max_nesting"`
+	Hotspots      int     `json:"hotspots" yaml:"hotspots"`
+}
+
+// MetricsThresholds 热点函数的判定阈值，值为 0 表示不检查该项
+type MetricsThresholds struct {
+	Cyclomatic int
+	Cognitive  int
+	LOC        int
+	Params     int
+	Nesting    int
+}
+
+// DefaultMetricsThresholds 默认阈值
+var DefaultMetricsThresholds = MetricsThresholds{
+	Cyclomatic: 10,
+	Cognitive:  15,
+	LOC:        80,
+	Params:     5,
+	Nesting:    4,
+}
+
+// Exceeded 返回函数超出阈值的指标名称列表
+func (t MetricsThresholds) Exceeded(m *FuncMetrics) []string {
+	var exceeded []string
+	if t.Cyclomatic > 0 && m.Cyclomatic > t.Cyclomatic {
+		exceeded = append(exceeded, "cyclomatic")
+	}
+	if t.Cognitive > 0 && m.Cognitive > t.Cognitive {
+		exceeded = append(exceeded, "cognitive")
+	}
+	if t.LOC > 0 && m.LOC > t.LOC {
+		exceeded = append(exceeded, "loc")
+	}
+	if t.Params > 0 && m.Params > t.Params {
+		exceeded = append(exceeded, "params")
+	}
+	if t.Nesting > 0 && m.MaxNesting > t.Nesting {
+		exceeded = append(exceeded, "nesting")
+	}
+	return exceeded
+}
+
+// AggregateByFile 按文件汇总函数指标
+func AggregateByFile(funcs []*FuncMetrics, thresholds MetricsThresholds) []*AggregateMetrics {
+	return aggregateMetrics(funcs, thresholds, func(m *FuncMetrics) string {
+		return m.File
+	})
+}
+
+// AggregateByPackage 按包(目录)汇总函数指标
+func AggregateByPackage(funcs []*FuncMetrics, thresholds MetricsThresholds) []*AggregateMetrics {
+	return aggregateMetrics(funcs, thresholds, func(m *FuncMetrics) string {
+		return filepath.Dir(m.File)
+	})
+}
+
+func aggregateMetrics(funcs []*FuncMetrics, thresholds MetricsThresholds, keyFn func(m *FuncMetrics) string) []*AggregateMetrics {
+	groups := make(map[string]*AggregateMetrics)
+	var keys []string
+	for _, m := range funcs {
+		key := keyFn(m)
+		agg, ok := groups[key]
+		if !ok {
+			agg = &AggregateMetrics{Name: key, Package: m.Package}
+			groups[key] = agg
+			keys = append(keys, key)
+		}
+		agg.Funcs++
+		agg.LOC += m.LOC
+		agg.AvgCyclomatic += float64(m.Cyclomatic)
+		agg.AvgCognitive += float64(m.Cognitive)
+		agg.MaxCyclomatic = max(agg.MaxCyclomatic, m.Cyclomatic)
+		agg.MaxCognitive = max(agg.MaxCognitive, m.Cognitive)
+		agg.MaxNesting = max(agg.MaxNesting, m.MaxNesting)
+		if len(thresholds.Exceeded(m)) > 0 {
+			agg.Hotspots++
+		}
+	}
+
+	sort.Strings(keys)
+	result := make([]*AggregateMetrics, 0, len(keys))
+	for _, key := range keys {
+		agg := groups[key]
+		agg.AvgCyclomatic /= float64(agg.Funcs)
+		agg.AvgCognitive /= float64(agg.Funcs)
+		result = append(result, agg)
+	}
+	return result
+}
+
+// SortFuncMetrics 按指定指标降序排序，by 取值 cyclomatic|cognitive|loc|params|nesting|returns|name
+func SortFuncMetrics(funcs []*FuncMetrics, by string) {
+	value := func(m *FuncMetrics) int {
+		switch by {
+		case "cognitive":
+			return m.Cognitive
+		case "loc":
+			return m.LOC
+		case "params":
+			return m.Params
+		case "nesting":
+			return m.MaxNesting
+		case "returns":
+			return m.Returns
+		default:
+			return m.Cyclomatic
+		}
+	}
+	sort.SliceStable(funcs, func(i, j int) bool {
+		if by == "name" {
+			return funcs[i].FullName() < funcs[j].FullName()
+		}
+		return value(funcs[i]) > value(funcs[j])
+	})
+}
+
+// computeFuncMetrics 计算单个函数声明的复杂度指标
+func computeFuncMetrics(fset *token.FileSet, filePath, pkgName string, fn *ast.FuncDecl) *FuncMetrics {
+	start := fset.Position(fn.Pos())
+	end := fset.Position(fn.End())
+	m := &FuncMetrics{
+		Name:       fn.Name.Name,
+		File:       filePath,
+		Package:    pkgName,
+		Line:       start.Line,
+		LOC:        end.Line - start.Line + 1,
+		Cyclomatic: 1,
+	}
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		m.Receiver = exprToString(fn.Recv.List[0].Type)
+	}
+	if fn.Type.Params != nil {
+		m.Params = fn.Type.Params.NumFields()
+	}
+	if fn.Body == nil {
+		return m
+	}
+
+	// 圈复杂度：分支、循环、case 以及逻辑运算符各加 1
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			m.Cyclomatic++
+		case *ast.CaseClause:
+			if t.List != nil {
+				m.Cyclomatic++
+			}
+		case *ast.CommClause:
+			if t.Comm != nil {
+				m.Cyclomatic++
+			}
+		case *ast.BinaryExpr:
+			if t.Op == token.LAND || t.Op == token.LOR {
+				m.Cyclomatic++
+			}
+		}
+		return true
+	})
+
+	w := &complexityWalker{}
+	w.walk(fn.Body, 0)
+	m.Cognitive = w.cognitive
+	m.MaxNesting = w.maxNesting
+	m.Returns = w.returns
+	return m
+}
+
+// complexityWalker 计算认知复杂度、最大嵌套深度和返回点数量
+type complexityWalker struct {
+	cognitive  int
+	maxNesting int
+	returns    int
+}
+
+// structure 进入一个控制结构：按嵌套层级加分并记录最大嵌套深度
+func (w *complexityWalker) structure(nesting int) {
+	w.cognitive += 1 + nesting
+	w.maxNesting = max(w.maxNesting, nesting+1)
+}
+
+func (w *complexityWalker) walk(node ast.Node, nesting int) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.IfStmt:
+			w.structure(nesting)
+			w.walkIf(t, nesting)
+			return false
+		case *ast.ForStmt:
+			w.structure(nesting)
+			w.walkOptional(t.Init, nesting)
+			w.walkOptional(t.Cond, nesting)
+			w.walkOptional(t.Post, nesting)
+			w.walk(t.Body, nesting+1)
+			return false
+		case *ast.RangeStmt:
+			w.structure(nesting)
+			w.walk(t.X, nesting)
+			w.walk(t.Body, nesting+1)
+			return false
+		case *ast.SwitchStmt:
+			w.structure(nesting)
+			w.walkOptional(t.Init, nesting)
+			w.walkOptional(t.Tag, nesting)
+			w.walk(t.Body, nesting+1)
+			return false
+		case *ast.TypeSwitchStmt:
+			w.structure(nesting)
+			w.walkOptional(t.Init, nesting)
+			w.walk(t.Body, nesting+1)
+			return false
+		case *ast.SelectStmt:
+			w.structure(nesting)
+			w.walk(t.Body, nesting+1)
+			return false
+		case *ast.FuncLit:
+			// 闭包本身不加分，但会增加内部结构的嵌套层级；其 return 不计入外层函数
+			inner := &complexityWalker{}
+			inner.walk(t.Body, nesting+1)
+			w.cognitive += inner.cognitive
+			w.maxNesting = max(w.maxNesting, inner.maxNesting, nesting+1)
+			return false
+		case *ast.BranchStmt:
+			if t.Label != nil || t.Tok == token.GOTO {
+				w.cognitive++
+			}
+		case *ast.ReturnStmt:
+			w.returns++
+		case *ast.BinaryExpr:
+			if t.Op == token.LAND || t.Op == token.LOR {
+				w.walkLogical(t, nesting)
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// walkIf 处理 if / else if / else 链，else 分支只加 1 分不叠加嵌套
+func (w *complexityWalker) walkIf(t *ast.IfStmt, nesting int) {
+	w.walkOptional(t.Init, nesting)
+	w.walk(t.Cond, nesting)
+	w.walk(t.Body, nesting+1)
+	switch e := t.Else.(type) {
+	case *ast.IfStmt:
+		w.cognitive++
+		w.walkIf(e, nesting)
+	case *ast.BlockStmt:
+		w.cognitive++
+		w.walk(e, nesting+1)
+	}
+}
+
+// walkLogical 同类逻辑运算符组成的序列只加 1 分
+func (w *complexityWalker) walkLogical(e *ast.BinaryExpr, nesting int) {
+	var ops []token.Token
+	var operands []ast.Expr
+	flattenLogical(e, &ops, &operands)
+	for i, op := range ops {
+		if i == 0 || op != ops[i-1] {
+			w.cognitive++
+		}
+	}
+	for _, operand := range operands {
+		w.walk(operand, nesting)
+	}
+}
+
+func (w *complexityWalker) walkOptional(node ast.Node, nesting int) {
+	if node == nil {
+		return
+	}
+	w.walk(node, nesting)
+}
+
+func flattenLogical(e ast.Expr, ops *[]token.Token, operands *[]ast.Expr) {
+	if b, ok := e.(*ast.BinaryExpr); ok && (b.Op == token.LAND || b.Op == token.LOR) {
+		flattenLogical(b.X, ops, operands)
+		*ops = append(*ops, b.Op)
+		flattenLogical(b.Y, ops, operands)
+		return
+	}
+	*operands = append(*operands, e)
+}
@@ -0,0 +1,74 @@
+package code
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestComputeFuncMetrics(t *testing.T) {
+	src := `package demo
+
+func Demo(a, b int, c string) (int, error) {
+	if a > 0 && b > 0 {
+		for i := 0; i < a; i++ {
+			if i%2 == 0 || i%3 == 0 {
+				continue
+			}
+		}
+	} else if a < 0 {
+		return -1, nil
+	} else {
+		switch c {
+		case "x":
+			return 1, nil
+		case "y":
+		default:
+		}
+	}
+	fn := func() int {
+		if b > 1 {
+			return 1
+		}
+		return 0
+	}
+	return fn(), nil
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "demo.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := computeFuncMetrics(fset, "demo.go", "demo", f.Decls[0].(*ast.FuncDecl))
+
+	// 1 + if + && + for + if + || + else if + case x + case y + 闭包中的 if
+	if m.Cyclomatic != 10 {
+		t.Errorf("cyclomatic = %d, want 10", m.Cyclomatic)
+	}
+	// if(1) + &&(1) + for(2) + if(3) + ||(1) + else if(1) + else(1) + switch(2) + 闭包 if(2)
+	if m.Cognitive != 14 {
+		t.Errorf("cognitive = %d, want 14", m.Cognitive)
+	}
+	if m.Params != 3 {
+		t.Errorf("params = %d, want 3", m.Params)
+	}
+	if m.MaxNesting != 3 {
+		t.Errorf("max nesting = %d, want 3", m.MaxNesting)
+	}
+	if m.Returns != 3 {
+		t.Errorf("returns = %d, want 3", m.Returns)
+	}
+	if m.LOC != 25 {
+		t.Errorf("loc = %d, want 25", m.LOC)
+	}
+
+	if exceeded := DefaultMetricsThresholds.Exceeded(m); len(exceeded) != 0 {
+		t.Errorf("unexpected hot spot: %v", exceeded)
+	}
+	aggs := AggregateByPackage([]*FuncMetrics{m, {File: "demo.go", Package: "demo", Cyclomatic: 20}}, DefaultMetricsThresholds)
+	if len(aggs) != 1 || aggs[0].Funcs != 2 || aggs[0].Hotspots != 1 || aggs[0].MaxCyclomatic != 20 {
+		t.Errorf("unexpected aggregate: %+v", aggs[0])
+	}
+}
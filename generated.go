@@ -0,0 +1,198 @@
+package code
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 生成代码的处理策略
+const (
+	// GeneratedPolicySkip 直接跳过
+	GeneratedPolicySkip = "skip"
+	// GeneratedPolicyStatic 使用静态模板生成摘要，不调用 AI
+	GeneratedPolicyStatic = "static"
+	// GeneratedPolicyGroup 同一个生成器的文件合并为一条摘要
+	GeneratedPolicyGroup = "group"
+)
+
+// GeneratorVendored 第三方代码使用的生成器名称
+const GeneratorVendored = "vendored"
+
+// generatedHeaderRegex Go 官方约定的生成代码头: ^// Code generated .* DO NOT EDIT\.$
+var generatedHeaderRegex = regexp.MustCompile(`^// Code generated (?:by (\S+?)\.? )?.*DO NOT EDIT\.$`)
+
+// generatedFilePatterns 按文件名识别的生成代码，值为生成器名称。
+// headerOnly 的文件名也常用于手写代码，只在有生成代码头时用于确定生成器
+var generatedFilePatterns = []struct {
+	pattern    string
+	generator  string
+	headerOnly bool
+}{
+	{"*.pb.go", "protoc-gen-go", false},
+	{"*.pb.gw.go", "grpc-gateway", false},
+	{"*.pb.validate.go", "protoc-gen-validate", false},
+	{"mock_*.go", "mockgen", false},
+	{"*_mock.go", "mockgen", false},
+	{"*_mocks.go", "mockgen", false},
+	{"bindata.go", "go-bindata", false},
+	{"*_bindata.go", "go-bindata", false},
+	{"zz_generated*.go", "controller-gen", false},
+	{"wire_gen.go", "wire", false},
+	{"*_gen.go", "go generate", true},
+	{"*.gen.go", "go generate", true},
+}
+
+// vendoredDirs 第三方代码所在的目录
+var vendoredDirs = []string{"vendor", "third_party", "thirdparty"}
+
+// GeneratedInfo 生成代码的识别结果
+type GeneratedInfo struct {
+	Generated bool
+	Generator string
+	Reason    string
+}
+
+// DetectGeneratedFile 读取文件头识别生成代码，同时按文件名和目录识别生成代码与第三方代码
+func DetectGeneratedFile(path string) (GeneratedInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return GeneratedInfo{}, err
+	}
+	defer file.Close()
+
+	// 生成代码头必须出现在 package 语句之前
+	var header bytes.Buffer
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		header.WriteString(line)
+		header.WriteString("\n")
+	}
+	return DetectGenerated(path, header.Bytes()), nil
+}
+
+// DetectGenerated 根据文件头内容和路径识别生成代码
+func DetectGenerated(path string, header []byte) GeneratedInfo {
+	for _, line := range strings.Split(string(header), "\n") {
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if match := generatedHeaderRegex.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			generator := strings.Trim(match[1], `"`)
+			if generator == "" {
+				generator = generatorByName(path, true)
+			}
+			if generator == "" {
+				generator = "unknown"
+			}
+			return GeneratedInfo{Generated: true, Generator: generator, Reason: "header: " + strings.TrimSpace(line)}
+		}
+	}
+
+	if generator := generatorByName(path, false); generator != "" {
+		return GeneratedInfo{Generated: true, Generator: generator, Reason: "file name pattern"}
+	}
+
+	for _, part := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		for _, vendored := range vendoredDirs {
+			if part == vendored {
+				return GeneratedInfo{Generated: true, Generator: GeneratorVendored, Reason: "vendored directory " + vendored}
+			}
+		}
+	}
+	return GeneratedInfo{}
+}
+
+// generatorByName 按文件名确定生成器，hasHeader 为 false 时忽略需要生成代码头的文件名
+func generatorByName(path string, hasHeader bool) string {
+	base := filepath.Base(path)
+	for _, p := range generatedFilePatterns {
+		if p.headerOnly && !hasHeader {
+			continue
+		}
+		if ok, _ := filepath.Match(p.pattern, base); ok {
+			return p.generator
+		}
+	}
+	return ""
+}
+
+// GeneratedDescription 生成代码静态摘要中的描述
+func GeneratedDescription(info GeneratedInfo, result *ParseResult) string {
+	if info.Generator == "protoc-gen-go" && result != nil {
+		return ProtoGeneratedDescription(result)
+	}
+	strBuilder := strings.Builder{}
+	if info.Generator == GeneratorVendored {
+		strBuilder.WriteString("第三方代码(静态摘要，未经 AI 分析)。")
+	} else {
+		strBuilder.WriteString("由 " + info.Generator + " 生成的代码(静态摘要，未经 AI 分析)。")
+	}
+	if result != nil {
+		writeDeclNames(&strBuilder, result)
+	}
+	return strBuilder.String()
+}
+
+// writeDeclNames 写入文件中声明的结构体、接口和导出函数名称
+func writeDeclNames(strBuilder *strings.Builder, result *ParseResult) {
+	if names := sortedKeys(result.Structs); len(names) > 0 {
+		strBuilder.WriteString("结构体: ")
+		strBuilder.WriteString(strings.Join(names, ", "))
+		strBuilder.WriteString("。")
+	}
+	if names := sortedKeys(result.Interfaces); len(names) > 0 {
+		strBuilder.WriteString("接口: ")
+		strBuilder.WriteString(strings.Join(names, ", "))
+		strBuilder.WriteString("。")
+	}
+	if len(result.ExportedFunc) > 0 {
+		var names []string
+		for _, fn := range result.ExportedFunc {
+			names = append(names, strings.SplitN(fn, "(", 2)[0])
+		}
+		strBuilder.WriteString("函数: ")
+		strBuilder.WriteString(strings.Join(names, ", "))
+		strBuilder.WriteString("。")
+	}
+}
+
+// GeneratedGroup 同一生成器生成的一组文件
+type GeneratedGroup struct {
+	Generator string
+	Files     []string
+	Results   []*ParseResult
+}
+
+// BuildGeneratedGroupSummary 把同一生成器的文件合并为一条静态摘要
+func BuildGeneratedGroupSummary(group *GeneratedGroup) (string, ParsedYAML, error) {
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString("由 " + group.Generator + " 生成的 " + strconv.Itoa(len(group.Files)) + " 个文件(静态摘要，未经 AI 分析)。")
+	strBuilder.WriteString("文件: " + strings.Join(group.Files, ", ") + "。")
+	merged := &ParseResult{
+		Structs:    make(map[string]*StructInfo),
+		Interfaces: make(map[string][]string),
+	}
+	for _, result := range group.Results {
+		for name, info := range result.Structs {
+			merged.Structs[name] = info
+		}
+		for name, methods := range result.Interfaces {
+			merged.Interfaces[name] = methods
+		}
+		merged.ExportedFunc = append(merged.ExportedFunc, result.ExportedFunc...)
+		if merged.PackageName == "" {
+			merged.PackageName = result.PackageName
+		}
+	}
+	writeDeclNames(&strBuilder, merged)
+	return BuildStaticSummary("generated:"+group.Generator, strBuilder.String(), merged)
+}
@@ -0,0 +1,30 @@
+package code
+
+import "testing"
+
+func TestDetectGenerated(t *testing.T) {
+	cases := []struct {
+		path      string
+		header    string
+		generated bool
+		generator string
+	}{
+		{"api/user.pb.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\n// versions:\n", true, "protoc-gen-go"},
+		{"kind_string.go", "// Code generated by \"stringer -type=Kind\"; DO NOT EDIT.\n", true, "stringer"},
+		{"store/mock_store.go", "// Code generated by MockGen. DO NOT EDIT.\n", true, "MockGen"},
+		{"assets.go", "// Code generated for package assets. DO NOT EDIT.\n", true, "unknown"},
+		{"store/mock_repo.go", "", true, "mockgen"},
+		{"api/types_gen.go", "// Code generated from schema.json. DO NOT EDIT.\n", true, "go generate"},
+		{"api/types_gen.go", "", false, ""},
+		{"api/client.gen.go", "// Package api 手写的客户端\n", false, ""},
+		{"third_party/lib/lib.go", "", true, GeneratorVendored},
+		{"service/user.go", "// Package service 用户服务\n// Code generated is mentioned but not a header\n", false, ""},
+		{"service/user.go", "package service\n// Code generated by x. DO NOT EDIT.\n", false, ""},
+	}
+	for _, c := range cases {
+		info := DetectGenerated(c.path, []byte(c.header))
+		if info.Generated != c.generated || info.Generator != c.generator {
+			t.Errorf("DetectGenerated(%s) = %+v, want generated=%v generator=%s", c.path, info, c.generated, c.generator)
+		}
+	}
+}
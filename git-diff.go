@@ -0,0 +1,119 @@
+package code
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitChanges git diff 得到的变更文件，路径与遍历 dir 时得到的路径格式一致
+type GitChanges struct {
+	// Changed 新增或修改的文件
+	Changed []string
+	// Deleted 删除的文件，重命名视为删除旧文件并新增新文件
+	Deleted []string
+}
+
+// GitChangedFiles 计算 dir 下相对于 since 的变更文件(包括工作区未提交的修改和未被忽略的新文件)，staged 为 true 时只计算暂存区的变更
+func GitChangedFiles(dir, since string, staged bool) (*GitChanges, error) {
+	if since == "" && !staged {
+		return nil, fmt.Errorf("either a revision or staged must be specified")
+	}
+	top, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	// 解析符号链接，保证与 git 输出的根目录一致
+	if resolved, err := filepath.EvalSymlinks(absDir); err == nil {
+		absDir = resolved
+	}
+
+	args := []string{"diff", "--name-status", "--no-renames", "-z"}
+	if staged {
+		args = append(args, "--cached")
+	} else {
+		args = append(args, since)
+	}
+	output, err := runGit(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	top = strings.TrimSpace(top)
+	changes := parseNameStatus(output, top, absDir, dir)
+	if staged {
+		return changes, nil
+	}
+	// git diff 不包含未跟踪的文件
+	untracked, err := runGit(dir, "ls-files", "--others", "--exclude-standard", "--full-name", "-z", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(strings.TrimRight(untracked, "\x00"), "\x00") {
+		if path, ok := gitPath(name, top, absDir, dir); name != "" && ok {
+			changes.Changed = append(changes.Changed, path)
+		}
+	}
+	return changes, nil
+}
+
+// parseNameStatus 解析 git diff --name-status -z 的输出，只保留 dir 目录下的文件
+func parseNameStatus(output, top, absDir, dir string) *GitChanges {
+	changes := &GitChanges{}
+	fields := strings.Split(strings.TrimRight(output, "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, name := fields[i], fields[i+1]
+		path, ok := gitPath(name, top, absDir, dir)
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(status, "D") {
+			changes.Deleted = append(changes.Deleted, path)
+		} else {
+			changes.Changed = append(changes.Changed, path)
+		}
+	}
+	return changes
+}
+
+// gitPath 把 git 输出的相对于仓库根目录的路径转换为 dir 下的路径，不在 dir 目录下时返回 false
+func gitPath(name, top, absDir, dir string) (string, bool) {
+	rel, err := filepath.Rel(absDir, filepath.Join(top, filepath.FromSlash(name)))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.Join(dir, rel), true
+}
+
+// GitHeadCommit 返回 dir 所在仓库的 HEAD 提交，工作区有未提交的修改时追加 -dirty
+func GitHeadCommit(dir string) (string, error) {
+	output, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	commit := strings.TrimSpace(output)
+	status, err := runGit(dir, "status", "--porcelain", "--", ".")
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(status) != "" {
+		commit += "-dirty"
+	}
+	return commit, nil
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
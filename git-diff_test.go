@@ -0,0 +1,70 @@
+package code
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGitChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("svc/a.go", "package svc\n")
+	write("svc/b.go", "package svc\n")
+	write("other/c.go", "package other\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "init")
+
+	write("svc/a.go", "package svc\n\nfunc A() {}\n")
+	write("other/c.go", "package other\n\nfunc C() {}\n")
+	if err := os.Remove(filepath.Join(repo, "svc/b.go")); err != nil {
+		t.Fatal(err)
+	}
+	write("svc/new.go", "package svc\n")
+	git("add", "svc/new.go")
+	write("svc/untracked.go", "package svc\n")
+	write("svc/ignored.go", "package svc\n")
+	write(".gitignore", "ignored.go\n")
+
+	dir := filepath.Join(repo, "svc")
+	changes, err := GitChangedFiles(dir, "HEAD", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &GitChanges{
+		Changed: []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "new.go"), filepath.Join(dir, "untracked.go")},
+		Deleted: []string{filepath.Join(dir, "b.go")},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("since HEAD = %+v, want %+v", changes, want)
+	}
+
+	changes, err = GitChangedFiles(dir, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, "new.go")}; !reflect.DeepEqual(changes.Changed, want) || len(changes.Deleted) != 0 {
+		t.Errorf("staged = %+v, want changed %v", changes, want)
+	}
+}
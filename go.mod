@@ -3,6 +3,7 @@ module codetest
 go 1.22.0
 
 require (
+	github.com/bmatcuk/doublestar/v4 v4.9.1
 	github.com/sashabaranov/go-openai v1.31.0
 	github.com/spf13/cobra v1.8.1
 	gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,146 @@
+package code
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	pyImportRegex     = regexp.MustCompile(`^import\s+(.+)$`)
+	pyFromImportRegex = regexp.MustCompile(`^from\s+(\S+)\s+import\s+`)
+	pyClassRegex      = regexp.MustCompile(`^class\s+([A-Za-z_]\w*)\s*(\(([^)]*)\))?\s*:`)
+	pyDefRegex        = regexp.MustCompile(`^(async\s+)?def\s+([A-Za-z_]\w*)\s*\(([^)]*)\)?`)
+	pyConstRegex      = regexp.MustCompile(`^([A-Z][A-Z0-9_]*)\s*(:[^=]+)?=\s*(.+)$`)
+)
+
+// pythonLanguage Python 语言插件，按缩进逐行提取顶层的导入、类、函数和常量
+type pythonLanguage struct{}
+
+func (l *pythonLanguage) Name() string {
+	return LanguagePython
+}
+
+func (l *pythonLanguage) Match(path string) bool {
+	return filepath.Ext(path) == ".py"
+}
+
+func (l *pythonLanguage) Parse(path string) (*ParseResult, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := parsePython(string(content))
+	result.FilePath = path
+	// 模块名为文件名，__init__.py 使用所在目录名
+	result.PackageName = strings.TrimSuffix(filepath.Base(path), ".py")
+	if result.PackageName == "__init__" {
+		result.PackageName = filepath.Base(filepath.Dir(path))
+	}
+	return result, nil
+}
+
+func (l *pythonLanguage) AnalysisPrompt(filename, code string) string {
+	return buildPythonAnalysisPrompt(filename, code)
+}
+
+// parsePython 提取 Python 源码中的导入、类及其方法、顶层函数和常量
+func parsePython(src string) *ParseResult {
+	result := &ParseResult{
+		Language:   LanguagePython,
+		Structs:    make(map[string]*StructInfo),
+		Interfaces: make(map[string][]string),
+	}
+
+	var class *StructInfo
+	methodIndent := -1
+	docQuote := ""
+	for _, rawLine := range strings.Split(src, "\n") {
+		line := strings.TrimRight(rawLine, " \t\r")
+		trimmed := strings.TrimSpace(line)
+
+		// 跳过多行字符串(文档字符串)
+		if docQuote != "" {
+			if strings.Contains(trimmed, docQuote) {
+				docQuote = ""
+			}
+			continue
+		}
+		for _, quote := range []string{`"""`, `'''`} {
+			if strings.Count(trimmed, quote)%2 == 1 {
+				docQuote = quote
+			}
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "@") {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent > 0 {
+			// 类中第一层的 def 为方法
+			if class != nil {
+				if match := pyDefRegex.FindStringSubmatch(trimmed); match != nil {
+					if methodIndent < 0 {
+						methodIndent = indent
+					}
+					if indent == methodIndent {
+						class.Methods = append(class.Methods, match[2]+"("+pyParams(match[3])+")")
+					}
+				}
+			}
+			continue
+		}
+
+		class, methodIndent = nil, -1
+		switch {
+		case pyImportRegex.MatchString(trimmed):
+			for _, name := range strings.Split(pyImportRegex.FindStringSubmatch(trimmed)[1], ",") {
+				result.Imports = append(result.Imports, strings.Fields(name)[0])
+			}
+		case pyFromImportRegex.MatchString(trimmed):
+			result.Imports = append(result.Imports, pyFromImportRegex.FindStringSubmatch(trimmed)[1])
+		case pyClassRegex.MatchString(trimmed):
+			match := pyClassRegex.FindStringSubmatch(trimmed)
+			class = &StructInfo{}
+			if bases := strings.TrimSpace(match[3]); bases != "" {
+				class.Fields = append(class.Fields, "bases: "+bases)
+			}
+			result.Structs[match[1]] = class
+		case pyDefRegex.MatchString(trimmed):
+			match := pyDefRegex.FindStringSubmatch(trimmed)
+			signature := match[2] + "(" + pyParams(match[3]) + ")"
+			if strings.HasPrefix(match[2], "_") {
+				result.UnexportedFunc = append(result.UnexportedFunc, signature)
+			} else {
+				result.ExportedFunc = append(result.ExportedFunc, signature)
+			}
+		case pyConstRegex.MatchString(trimmed):
+			match := pyConstRegex.FindStringSubmatch(trimmed)
+			result.Constants = append(result.Constants, match[1]+" = "+shortText(match[3], 60))
+		}
+	}
+	return result
+}
+
+// pyParams 规范化参数列表，去掉 self/cls 和默认值中的多余空白
+func pyParams(params string) string {
+	var names []string
+	for _, param := range strings.Split(params, ",") {
+		param = strings.Join(strings.Fields(param), " ")
+		if param == "" || param == "self" || param == "cls" {
+			continue
+		}
+		names = append(names, param)
+	}
+	return strings.Join(names, ", ")
+}
+
+// shortText 按字符截断过长的文本
+func shortText(s string, max int) string {
+	s = strings.TrimSpace(s)
+	if runes := []rune(s); len(runes) > max {
+		return string(runes[:max]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+package code
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	sqlLineCommentRegex  = regexp.MustCompile(`--[^\n]*`)
+	sqlBlockCommentRegex = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	sqlCreateTableRegex  = regexp.MustCompile(`(?is)^CREATE\s+(?:TEMP(?:ORARY)?\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([^\s(]+)\s*\((.*)\)`)
+	// goose 和 sql-migrate 在同一个文件中用注释区分升级和回滚
+	sqlDownMarkerRegex = regexp.MustCompile(`(?im)^--\s*\+(goose|migrate)\s+Down`)
+	// 迁移文件名中的版本号，例如 0001_init.up.sql 或 20240101120000_add_users.sql
+	sqlVersionRegex = regexp.MustCompile(`^(\d+)[_-]`)
+)
+
+// SQL 表定义中不是列的约束子句
+var sqlConstraintPrefixes = []string{"PRIMARY", "CONSTRAINT", "FOREIGN", "UNIQUE", "INDEX", "KEY", "CHECK", "FULLTEXT", "SPATIAL"}
+
+// sqlLanguage SQL 迁移文件插件，提取建表语句的列定义以及其他 DDL 语句
+type sqlLanguage struct{}
+
+func (l *sqlLanguage) Name() string {
+	return LanguageSQL
+}
+
+func (l *sqlLanguage) Match(path string) bool {
+	return filepath.Ext(path) == ".sql"
+}
+
+func (l *sqlLanguage) Parse(path string) (*ParseResult, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := parseSQL(string(content))
+	result.FilePath = path
+	// 迁移文件使用版本号作为包名，否则使用文件名
+	base := filepath.Base(path)
+	if match := sqlVersionRegex.FindStringSubmatch(base); match != nil {
+		result.PackageName = match[1]
+	} else {
+		result.PackageName = strings.TrimSuffix(base, ".sql")
+	}
+	return result, nil
+}
+
+func (l *sqlLanguage) AnalysisPrompt(filename, code string) string {
+	return buildSQLAnalysisPrompt(filename, code)
+}
+
+// parseSQL 提取 SQL 中的建表语句和其他语句，回滚部分只记录在 Declarations 中
+func parseSQL(src string) *ParseResult {
+	result := &ParseResult{
+		Language:   LanguageSQL,
+		Structs:    make(map[string]*StructInfo),
+		Interfaces: make(map[string][]string),
+	}
+
+	down := ""
+	if loc := sqlDownMarkerRegex.FindStringIndex(src); loc != nil {
+		src, down = src[:loc[0]], src[loc[1]:]
+	}
+	for _, stmt := range splitSQLStatements(src) {
+		if match := sqlCreateTableRegex.FindStringSubmatch(stmt); match != nil {
+			table := &StructInfo{Fields: sqlColumns(match[2])}
+			result.Structs[strings.Trim(match[1], "`\"[]")] = table
+			continue
+		}
+		result.Declarations = append(result.Declarations, sqlStatementSummary(stmt))
+	}
+	for _, stmt := range splitSQLStatements(down) {
+		result.Declarations = append(result.Declarations, "down: "+sqlStatementSummary(stmt))
+	}
+	return result
+}
+
+// splitSQLStatements 去掉注释后按分号切分语句
+func splitSQLStatements(src string) []string {
+	src = sqlBlockCommentRegex.ReplaceAllString(src, "")
+	src = sqlLineCommentRegex.ReplaceAllString(src, "")
+	var stmts []string
+	for _, stmt := range strings.Split(src, ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+// sqlColumns 按顶层逗号拆分列定义，返回 "列名 类型"
+func sqlColumns(body string) []string {
+	var columns []string
+	depth, start := 0, 0
+	parts := []string{}
+	for i, c := range body {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, body[start:])
+
+	for _, part := range parts {
+		fields := strings.Fields(part)
+		if len(fields) < 2 {
+			continue
+		}
+		constraint := false
+		for _, prefix := range sqlConstraintPrefixes {
+			if strings.EqualFold(fields[0], prefix) {
+				constraint = true
+				break
+			}
+		}
+		if constraint {
+			continue
+		}
+		// DECIMAL(10, 2) 之类的类型中可能包含空白
+		columnType := fields[1]
+		for j := 2; j < len(fields) && strings.Count(columnType, "(") > strings.Count(columnType, ")"); j++ {
+			columnType += fields[j]
+		}
+		columns = append(columns, strings.Trim(fields[0], "`\"[]")+" "+columnType)
+	}
+	return columns
+}
+
+// sqlStatementSummary 把语句压缩为一行并截断
+func sqlStatementSummary(stmt string) string {
+	return shortText(strings.Join(strings.Fields(stmt), " "), 100)
+}
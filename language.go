@@ -0,0 +1,83 @@
+package code
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// 内置语言的名称
+const (
+	LanguageGo     = "go"
+	LanguagePython = "python"
+	LanguageSQL    = "sql"
+)
+
+// Language 语言插件：识别属于该语言的文件、静态解析文件并提供 AI 分析使用的提示词
+type Language interface {
+	// Name 语言名称，用于命令行参数和总结文件
+	Name() string
+	// Match 判断文件是否属于该语言
+	Match(path string) bool
+	// Parse 不调用 AI 静态提取文件中的导入、类型、函数等信息
+	Parse(path string) (*ParseResult, error)
+	// AnalysisPrompt 分析单个文件的提示词
+	AnalysisPrompt(filename, code string) string
+}
+
+// goLanguage Go 语言插件，使用 go/parser 解析
+type goLanguage struct {
+	parser *Parser
+}
+
+// NewGoLanguage 创建使用指定解析器的 Go 语言插件
+func NewGoLanguage(parser *Parser) Language {
+	return &goLanguage{parser: parser}
+}
+
+func (l *goLanguage) Name() string {
+	return LanguageGo
+}
+
+func (l *goLanguage) Match(path string) bool {
+	return filepath.Ext(path) == ".go"
+}
+
+func (l *goLanguage) Parse(path string) (*ParseResult, error) {
+	return l.parser.ParseByFile(path)
+}
+
+func (l *goLanguage) AnalysisPrompt(filename, code string) string {
+	return buildFileAnalysisPrompt(filename, code)
+}
+
+// LanguageNames 内置语言的名称列表
+var LanguageNames = []string{LanguageGo, LanguagePython, LanguageSQL}
+
+// LookupLanguages 根据名称返回语言插件，Go 插件使用指定的解析器
+func LookupLanguages(names []string, parser *Parser) ([]Language, error) {
+	var languages []Language
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case LanguageGo:
+			languages = append(languages, NewGoLanguage(parser))
+		case LanguagePython:
+			languages = append(languages, &pythonLanguage{})
+		case LanguageSQL:
+			languages = append(languages, &sqlLanguage{})
+		default:
+			return nil, fmt.Errorf("unsupported language: %s", name)
+		}
+	}
+	return languages, nil
+}
+
+// LanguageFor 返回第一个匹配文件的语言插件，没有匹配时返回 nil
+func LanguageFor(path string, languages []Language) Language {
+	for _, lang := range languages {
+		if lang.Match(path) {
+			return lang
+		}
+	}
+	return nil
+}
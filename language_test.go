@@ -0,0 +1,115 @@
+package code
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePython(t *testing.T) {
+	src := `"""用户服务
+
+class NotAClass:
+"""
+import os, sys as system
+from app.models import User
+
+MAX_USERS: int = 100
+
+
+class UserService(BaseService):
+    """用户相关操作"""
+
+    def __init__(self, db):
+        self.db = db
+
+    def get(self, user_id: int) -> User:
+        def inner():
+            pass
+        return self.db.get(user_id)
+
+
+@app.route("/users")
+async def list_users(limit=10):
+    return []
+
+
+def _helper():
+    pass
+`
+	result := parsePython(src)
+	if want := []string{"os", "sys", "app.models"}; !reflect.DeepEqual(result.Imports, want) {
+		t.Errorf("imports = %v, want %v", result.Imports, want)
+	}
+	if _, ok := result.Structs["NotAClass"]; ok {
+		t.Error("class inside docstring should be ignored")
+	}
+	service, ok := result.Structs["UserService"]
+	if !ok {
+		t.Fatalf("UserService not found: %v", result.Structs)
+	}
+	if want := []string{"__init__(db)", "get(user_id: int)"}; !reflect.DeepEqual(service.Methods, want) {
+		t.Errorf("methods = %v, want %v", service.Methods, want)
+	}
+	if want := []string{"list_users(limit=10)"}; !reflect.DeepEqual(result.ExportedFunc, want) {
+		t.Errorf("exported funcs = %v, want %v", result.ExportedFunc, want)
+	}
+	if want := []string{"_helper()"}; !reflect.DeepEqual(result.UnexportedFunc, want) {
+		t.Errorf("unexported funcs = %v, want %v", result.UnexportedFunc, want)
+	}
+	if want := []string{"MAX_USERS = 100"}; !reflect.DeepEqual(result.Constants, want) {
+		t.Errorf("constants = %v, want %v", result.Constants, want)
+	}
+}
+
+func TestParseSQL(t *testing.T) {
+	src := `-- +goose Up
+/* 用户表 */
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT PRIMARY KEY,
+    name VARCHAR(64) NOT NULL, -- 名称
+    price DECIMAL(10, 2),
+    PRIMARY KEY (id),
+    CONSTRAINT fk_org FOREIGN KEY (org_id) REFERENCES orgs(id)
+);
+CREATE INDEX idx_users_name ON users (name);
+
+-- +goose Down
+DROP TABLE users;
+`
+	result := parseSQL(src)
+	users, ok := result.Structs["users"]
+	if !ok {
+		t.Fatalf("users table not found: %v", result.Structs)
+	}
+	if want := []string{"id BIGINT", "name VARCHAR(64)", "price DECIMAL(10,2)"}; !reflect.DeepEqual(users.Fields, want) {
+		t.Errorf("columns = %v, want %v", users.Fields, want)
+	}
+	if want := []string{"CREATE INDEX idx_users_name ON users (name)", "down: DROP TABLE users"}; !reflect.DeepEqual(result.Declarations, want) {
+		t.Errorf("declarations = %v, want %v", result.Declarations, want)
+	}
+}
+
+func TestLanguageFor(t *testing.T) {
+	languages, err := LookupLanguages(LanguageNames, NewParser())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"main.go":                     LanguageGo,
+		"app/service.py":              LanguagePython,
+		"migrations/0001_init.up.sql": LanguageSQL,
+		"web/index.ts":                "",
+	}
+	for path, want := range cases {
+		got := ""
+		if lang := LanguageFor(path, languages); lang != nil {
+			got = lang.Name()
+		}
+		if got != want {
+			t.Errorf("LanguageFor(%s) = %q, want %q", path, got, want)
+		}
+	}
+	if _, err := LookupLanguages([]string{"cobol"}, nil); err == nil {
+		t.Error("expected error for unsupported language")
+	}
+}
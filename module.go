@@ -0,0 +1,276 @@
+package code
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Module go.mod 定义的模块
+type Module struct {
+	// Path 模块路径，例如 github.com/foo/bar
+	Path string
+	// Dir go.mod 所在目录
+	Dir       string
+	GoVersion string
+}
+
+// Workspace 分析目录中的所有模块，以及可选的 go.work
+type Workspace struct {
+	Root string
+	// GoWork go.work 文件路径，不存在时为空
+	GoWork  string
+	Modules []*Module
+}
+
+// DiscoverModules 查找 root 下所有的 go.mod 和 go.work，root 本身不是模块时向上查找所属的模块
+//
+// 遍历使用 opts 中的过滤规则和符号链接设置(不应用 include 通配符)，无法读取的目录和无法解析的 go.mod、go.work
+// 通过 opts.OnError 报告后跳过，只有 root 无法读取时返回错误
+func DiscoverModules(root string, opts WalkOptions) (*Workspace, error) {
+	if opts.OnError == nil {
+		opts.OnError = func(path string, err error) {
+			fmt.Printf("Walk error: %s: %v\n", path, err)
+		}
+	}
+	if opts.Filter != nil {
+		opts.Filter = opts.Filter.withoutIncludes()
+	}
+	opts.Match = func(path string) bool {
+		name := filepath.Base(path)
+		return name == "go.mod" || name == "go.work"
+	}
+
+	ws := &Workspace{Root: root}
+	seen := make(map[string]bool)
+	addModule := func(dir string) {
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			return
+		}
+		seen[dir] = true
+		path := filepath.Join(dir, "go.mod")
+		mod, err := ParseGoMod(path)
+		if err != nil {
+			opts.OnError(path, err)
+			return
+		}
+		mod.Dir = dir
+		ws.Modules = append(ws.Modules, mod)
+	}
+
+	err := Walk(root, opts, func(path string) {
+		switch filepath.Base(path) {
+		case "go.mod":
+			addModule(filepath.Dir(path))
+		case "go.work":
+			if ws.GoWork == "" || filepath.Dir(path) == filepath.Clean(root) {
+				ws.GoWork = path
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// go.work 中 use 的模块可能位于 root 之外
+	if ws.GoWork != "" {
+		uses, err := parseGoWorkUses(ws.GoWork)
+		if err != nil {
+			opts.OnError(ws.GoWork, err)
+		}
+		for _, use := range uses {
+			addModule(filepath.Join(filepath.Dir(ws.GoWork), use))
+		}
+	}
+
+	// 分析的是模块中的子目录
+	if !seen[filepath.Clean(root)] {
+		if dir := findEnclosingModuleDir(root); dir != "" {
+			addModule(dir)
+		}
+	}
+
+	sort.Slice(ws.Modules, func(i, j int) bool {
+		return ws.Modules[i].Dir < ws.Modules[j].Dir
+	})
+	return ws, nil
+}
+
+// ParseGoMod 读取 go.mod 中的 module 和 go 指令
+func ParseGoMod(path string) (*Module, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	mod := &Module{Dir: filepath.Dir(path)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(stripGoModComment(scanner.Text()))
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			mod.Path = unquoteGoMod(fields[1])
+		case "go":
+			mod.GoVersion = fields[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if mod.Path == "" {
+		return nil, fmt.Errorf("%s: missing module directive", path)
+	}
+	return mod, nil
+}
+
+// parseGoWorkUses 读取 go.work 中 use 的模块目录，支持单行和块两种写法
+func parseGoWorkUses(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var uses []string
+	inBlock := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(stripGoModComment(scanner.Text()))
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			uses = append(uses, unquoteGoMod(line))
+		case line == "use (":
+			inBlock = true
+		case strings.HasPrefix(line, "use "):
+			uses = append(uses, unquoteGoMod(strings.TrimSpace(strings.TrimPrefix(line, "use "))))
+		}
+	}
+	return uses, scanner.Err()
+}
+
+func stripGoModComment(line string) string {
+	if i := strings.Index(line, "//"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+func unquoteGoMod(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
+
+// findEnclosingModuleDir 从 dir 向上查找包含 go.mod 的目录
+func findEnclosingModuleDir(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(abs, "go.mod")); err == nil {
+			return abs
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return ""
+		}
+		abs = parent
+	}
+}
+
+func mustAbs(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+// ModuleFor 返回文件所属的模块(go.mod 所在目录最深的模块)，不属于任何模块时返回 nil
+func (w *Workspace) ModuleFor(path string) *Module {
+	abs := mustAbs(path)
+	var found *Module
+	for _, mod := range w.Modules {
+		dir := mustAbs(mod.Dir)
+		if abs != dir && !strings.HasPrefix(abs, dir+string(filepath.Separator)) {
+			continue
+		}
+		if found == nil || len(dir) > len(mustAbs(found.Dir)) {
+			found = mod
+		}
+	}
+	return found
+}
+
+// ImportPath 返回文件所在包的导入路径，不属于任何模块时返回空
+func (w *Workspace) ImportPath(path string) string {
+	mod := w.ModuleFor(path)
+	if mod == nil {
+		return ""
+	}
+	rel, err := filepath.Rel(mustAbs(mod.Dir), mustAbs(filepath.Dir(path)))
+	if err != nil || rel == "." {
+		return mod.Path
+	}
+	return mod.Path + "/" + filepath.ToSlash(rel)
+}
+
+// ModuleSummaryName 模块摘要文件名，模块路径中的 / 替换为 _
+func ModuleSummaryName(modulePath string) string {
+	return strings.ReplaceAll(modulePath, "/", "_") + ".md"
+}
+
+// Index 生成工作区索引：每个模块的路径、目录、Go 版本、包列表和模块摘要文件
+func (w *Workspace) Index(files []string) string {
+	// 模块 -> 导入路径 -> 文件数
+	packages := make(map[*Module]map[string]int)
+	for _, path := range files {
+		mod := w.ModuleFor(path)
+		if mod == nil {
+			continue
+		}
+		if packages[mod] == nil {
+			packages[mod] = make(map[string]int)
+		}
+		packages[mod][w.ImportPath(path)]++
+	}
+
+	var strBuilder strings.Builder
+	strBuilder.WriteString(fmt.Sprintf("工作区: %s\n", w.Root))
+	if w.GoWork != "" {
+		strBuilder.WriteString(fmt.Sprintf("go.work: %s\n", w.GoWork))
+	}
+	strBuilder.WriteString(fmt.Sprintf("模块数: %d\n", len(w.Modules)))
+	strBuilder.WriteString("---\n")
+	for _, mod := range w.Modules {
+		files := 0
+		for _, count := range packages[mod] {
+			files += count
+		}
+		strBuilder.WriteString(fmt.Sprintf("模块: %s\n", mod.Path))
+		strBuilder.WriteString(fmt.Sprintf("目录: %s\n", mod.Dir))
+		if mod.GoVersion != "" {
+			strBuilder.WriteString(fmt.Sprintf("Go 版本: %s\n", mod.GoVersion))
+		}
+		strBuilder.WriteString(fmt.Sprintf("文件数: %d\n", files))
+		strBuilder.WriteString("包: ")
+		strBuilder.WriteString(strings.Join(sortedKeys(packages[mod]), ","))
+		strBuilder.WriteString("\n")
+		strBuilder.WriteString(fmt.Sprintf("摘要: modules/%s\n", ModuleSummaryName(mod.Path)))
+		strBuilder.WriteString("---\n")
+	}
+	return strBuilder.String()
+}
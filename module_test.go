@@ -0,0 +1,77 @@
+package code
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverModules(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.work":              "go 1.22\n\nuse (\n\t./api\n\t./svc // 服务\n)\n",
+		"api/go.mod":           "module github.com/acme/api\n\ngo 1.21\n",
+		"api/v1/user.go":       "package v1\n",
+		"svc/go.mod":           "module \"github.com/acme/svc\"\n\ngo 1.22\n",
+		"svc/main.go":          "package main\n",
+		"svc/tools/go.mod":     "module github.com/acme/svc/tools\n",
+		"svc/tools/gen/gen.go": "package gen\n",
+		"vendor/x/go.mod":      "module x\n",
+		"broken/go.mod":        "go 1.22\n",
+		"ignored/go.mod":       "module ignored\n",
+		".gitignore":           "ignored/\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filter, err := NewFileFilter(root, []string{"**/*.go"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failed []string
+	ws, err := DiscoverModules(root, WalkOptions{Filter: filter, OnError: func(path string, err error) {
+		failed = append(failed, path)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 无法解析的 go.mod 被报告并跳过，被忽略的目录不会被遍历
+	if len(failed) != 1 || failed[0] != filepath.Join(root, "broken", "go.mod") {
+		t.Errorf("failed = %v", failed)
+	}
+	if ws.GoWork != filepath.Join(root, "go.work") {
+		t.Errorf("GoWork = %s", ws.GoWork)
+	}
+	var paths []string
+	for _, mod := range ws.Modules {
+		paths = append(paths, mod.Path)
+	}
+	if got := strings.Join(paths, ","); got != "github.com/acme/api,github.com/acme/svc,github.com/acme/svc/tools" {
+		t.Errorf("modules = %s", got)
+	}
+
+	cases := map[string]string{
+		"api/v1/user.go":       "github.com/acme/api/v1",
+		"svc/main.go":          "github.com/acme/svc",
+		"svc/tools/gen/gen.go": "github.com/acme/svc/tools/gen",
+		"README.md":            "",
+	}
+	for name, want := range cases {
+		if got := ws.ImportPath(filepath.Join(root, name)); got != want {
+			t.Errorf("ImportPath(%s) = %q, want %q", name, got, want)
+		}
+	}
+
+	index := ws.Index([]string{filepath.Join(root, "api/v1/user.go"), filepath.Join(root, "svc/main.go")})
+	if !strings.Contains(index, "包: github.com/acme/api/v1\n") || !strings.Contains(index, "摘要: modules/github.com_acme_svc.md\n") {
+		t.Errorf("unexpected index:\n%s", index)
+	}
+}
@@ -0,0 +1,333 @@
+package code
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// OpenAPI OpenAPI 3 文档
+type OpenAPI struct {
+	OpenAPI    string                                  `json:"openapi" yaml:"openapi"`
+	Info       OpenAPIInfo                             `json:"info" yaml:"info"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths" yaml:"paths"`
+	Components OpenAPIComponents                       `json:"components" yaml:"components"`
+
+	// Warnings 生成过程中发现的问题，例如路径和方法相同的重复路由，不写入文档
+	Warnings []string `json:"-" yaml:"-"`
+}
+
+// OpenAPIInfo 文档基本信息
+type OpenAPIInfo struct {
+	Title   string `json:"title" yaml:"title"`
+	Version string `json:"version" yaml:"version"`
+}
+
+// OpenAPIComponents 可复用的结构定义
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+}
+
+// OpenAPIOperation 单个接口
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId" yaml:"operationId"`
+	Summary     string                      `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string                      `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses" yaml:"responses"`
+
+	// endpoint 生成该接口的路由，用于补充 AI 描述
+	endpoint *Endpoint
+}
+
+// OpenAPIParameter 路径或查询参数
+type OpenAPIParameter struct {
+	Name     string  `json:"name" yaml:"name"`
+	In       string  `json:"in" yaml:"in"`
+	Required bool    `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema   *Schema `json:"schema" yaml:"schema"`
+}
+
+// OpenAPIRequestBody 请求体
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required,omitempty" yaml:"required,omitempty"`
+	Content  map[string]*OpenAPIMediaType `json:"content" yaml:"content"`
+}
+
+// OpenAPIResponse 响应
+type OpenAPIResponse struct {
+	Description string                       `json:"description" yaml:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// OpenAPIMediaType 某种内容类型的结构
+type OpenAPIMediaType struct {
+	Schema *Schema `json:"schema" yaml:"schema"`
+}
+
+// Schema JSON Schema 子集
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty" yaml:"allOf,omitempty"`
+}
+
+var (
+	// gin/echo 的 :id 和 *path 参数
+	colonParamRegex = regexp.MustCompile(`[:*]([A-Za-z_][A-Za-z0-9_]*)`)
+	// chi/mux 的 {id} 或 {id:[0-9]+} 参数
+	braceParamRegex = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)(:[^}]*)?\}`)
+)
+
+// openAPIPath 把各路由框架的路径参数统一为 OpenAPI 的 {name} 形式
+func openAPIPath(path string) (string, []string) {
+	path = braceParamRegex.ReplaceAllString(path, "{$1}")
+	path = colonParamRegex.ReplaceAllString(path, "{$1}")
+	var params []string
+	for _, match := range braceParamRegex.FindAllStringSubmatch(path, -1) {
+		params = append(params, match[1])
+	}
+	if path == "" {
+		path = "/"
+	}
+	return path, params
+}
+
+// BuildOpenAPI 根据路由和结构体定义生成 OpenAPI 文档
+func BuildOpenAPI(title, version string, endpoints []*Endpoint, results []*ParseResult) *OpenAPI {
+	b := &openAPIBuilder{
+		structs:      make(map[string]*StructInfo),
+		filePackages: make(map[string]string),
+		operationIDs: make(map[string]bool),
+		doc: &OpenAPI{
+			OpenAPI:    "3.0.3",
+			Info:       OpenAPIInfo{Title: title, Version: version},
+			Paths:      make(map[string]map[string]*OpenAPIOperation),
+			Components: OpenAPIComponents{Schemas: make(map[string]*Schema)},
+		},
+	}
+	for _, result := range results {
+		b.filePackages[result.FilePath] = result.PackageName
+		for name, info := range result.Structs {
+			b.structs[result.PackageName+"."+name] = info
+		}
+	}
+
+	for _, ep := range endpoints {
+		path, params := openAPIPath(ep.Path)
+		for _, method := range endpointMethods(ep.Method) {
+			if b.doc.Paths[path] == nil {
+				b.doc.Paths[path] = make(map[string]*OpenAPIOperation)
+			}
+			// 保留先注册的路由，重复的路由记录为警告
+			if existing := b.doc.Paths[path][method]; existing != nil {
+				b.doc.Warnings = append(b.doc.Warnings, fmt.Sprintf("duplicate route %s %s: %s (%s:%d) conflicts with %s (%s:%d)",
+					strings.ToUpper(method), path, ep.Handler, ep.File, ep.Line,
+					existing.endpoint.Handler, existing.endpoint.File, existing.endpoint.Line))
+				continue
+			}
+			b.doc.Paths[path][method] = b.operation(ep, method, path, params)
+		}
+	}
+	return b.doc
+}
+
+// openAPIMethods OpenAPI 路径中可以定义的所有 HTTP 方法
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// endpointMethods 拆分路由的 HTTP 方法，ANY 展开为所有方法
+func endpointMethods(method string) []string {
+	if method == "ANY" {
+		return openAPIMethods
+	}
+	var methods []string
+	for _, m := range strings.Split(method, ",") {
+		methods = append(methods, strings.ToLower(strings.TrimSpace(m)))
+	}
+	return methods
+}
+
+type openAPIBuilder struct {
+	doc *OpenAPI
+	// structs 带包名的结构体名，例如 api.User
+	structs map[string]*StructInfo
+	// filePackages 文件 -> 包名，用于确定处理函数中类型所在的包
+	filePackages map[string]string
+	// operationIDs 已经使用的 operationId
+	operationIDs map[string]bool
+}
+
+// operationIDRegex operationId 中不允许的字符
+var operationIDRegex = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// operationID 由方法和路径生成唯一的 operationId，例如 put /users/{id} -> put_users_id，重复时加上数字后缀
+func (b *openAPIBuilder) operationID(method, path string) string {
+	id := strings.Trim(operationIDRegex.ReplaceAllString(method+"_"+path, "_"), "_")
+	unique := id
+	for i := 2; b.operationIDs[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", id, i)
+	}
+	b.operationIDs[unique] = true
+	return unique
+}
+
+// endpointPackage 路由处理函数所在的包，请求和响应类型相对于该包
+func (b *openAPIBuilder) endpointPackage(ep *Endpoint) string {
+	if pkg, _, ok := strings.Cut(ep.HandlerKey, "."); ok {
+		return pkg
+	}
+	return b.filePackages[ep.File]
+}
+
+// qualifiedTypeName 去掉指针并补全包名，例如 *User -> api.User，dto.User 保持不变
+func qualifiedTypeName(goType, pkg string) string {
+	goType = strings.TrimPrefix(goType, "*")
+	if strings.Contains(goType, ".") || pkg == "" {
+		return goType
+	}
+	return pkg + "." + goType
+}
+
+func (b *openAPIBuilder) operation(ep *Endpoint, method, path string, pathParams []string) *OpenAPIOperation {
+	pkg := b.endpointPackage(ep)
+	op := &OpenAPIOperation{
+		OperationID: b.operationID(method, path),
+		Summary:     ep.Handler,
+		Responses:   make(map[string]*OpenAPIResponse),
+		endpoint:    ep,
+	}
+	if segments := strings.Split(strings.Trim(ep.Path, "/"), "/"); segments[0] != "" {
+		op.Tags = []string{segments[0]}
+	}
+	for _, name := range pathParams {
+		op.Parameters = append(op.Parameters, &OpenAPIParameter{
+			Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"},
+		})
+	}
+
+	if ep.RequestType != "" {
+		if method == "get" || method == "delete" || method == "head" {
+			// 无请求体的方法把请求结构体字段作为查询参数
+			requestType := qualifiedTypeName(ep.RequestType, pkg)
+			if info, ok := b.structs[requestType]; ok {
+				for _, field := range info.FieldDefs {
+					if name := jsonFieldName(field); name != "" && !field.Embedded {
+						op.Parameters = append(op.Parameters, &OpenAPIParameter{
+							Name: name, In: "query", Schema: b.schema(field.Type, typePackage(requestType)),
+						})
+					}
+				}
+			}
+		} else {
+			op.RequestBody = &OpenAPIRequestBody{
+				Required: true,
+				Content:  map[string]*OpenAPIMediaType{"application/json": {Schema: b.schema(ep.RequestType, pkg)}},
+			}
+		}
+	}
+
+	response := &OpenAPIResponse{Description: "OK"}
+	if ep.ResponseType != "" {
+		response.Content = map[string]*OpenAPIMediaType{"application/json": {Schema: b.schema(ep.ResponseType, pkg)}}
+	}
+	op.Responses["200"] = response
+	return op
+}
+
+// schema 把 Go 类型转换为 Schema，项目中的结构体以 包名.结构体名 注册到 components，pkg 为类型所在的包
+func (b *openAPIBuilder) schema(goType, pkg string) *Schema {
+	goType = strings.TrimPrefix(goType, "*")
+	switch {
+	case strings.HasPrefix(goType, "[]"):
+		if goType == "[]byte" {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(goType[2:], pkg)}
+	case strings.HasPrefix(goType, "map["):
+		if end := strings.Index(goType, "]"); end > 0 {
+			return &Schema{Type: "object", AdditionalProperties: b.schema(goType[end+1:], pkg)}
+		}
+	}
+
+	switch goType {
+	case "string":
+		return &Schema{Type: "string"}
+	case "bool":
+		return &Schema{Type: "boolean"}
+	case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32":
+		return &Schema{Type: "integer", Format: "int32"}
+	case "int64", "uint64":
+		return &Schema{Type: "integer", Format: "int64"}
+	case "float32":
+		return &Schema{Type: "number", Format: "float"}
+	case "float64":
+		return &Schema{Type: "number", Format: "double"}
+	case "time.Time":
+		return &Schema{Type: "string", Format: "date-time"}
+	case "interface{}", "any", "json.RawMessage":
+		return &Schema{}
+	case "gin.H", "echo.Map":
+		return &Schema{Type: "object"}
+	}
+
+	name := qualifiedTypeName(goType, pkg)
+	info, ok := b.structs[name]
+	if !ok {
+		return &Schema{Type: "object"}
+	}
+	if _, registered := b.doc.Components.Schemas[name]; !registered {
+		// 先占位，避免递归结构体死循环
+		object := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		b.doc.Components.Schemas[name] = object
+		var embedded []*Schema
+		for _, field := range info.FieldDefs {
+			if field.Embedded && field.JSONName == "" {
+				embedded = append(embedded, b.schema(field.Type, typePackage(name)))
+				continue
+			}
+			jsonName := jsonFieldName(field)
+			if jsonName == "" {
+				continue
+			}
+			object.Properties[jsonName] = b.schema(field.Type, typePackage(name))
+			if !field.OmitEmpty && !strings.HasPrefix(field.Type, "*") {
+				object.Required = append(object.Required, jsonName)
+			}
+		}
+		if len(embedded) > 0 {
+			b.doc.Components.Schemas[name] = &Schema{AllOf: append(embedded, object)}
+		}
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// jsonFieldName 返回字段序列化后的名称，不导出或 json:"-" 的字段返回空
+func jsonFieldName(field *StructField) string {
+	if field.JSONName == "-" {
+		return ""
+	}
+	if field.JSONName != "" {
+		return field.JSONName
+	}
+	if len(field.Name) == 0 || !isUpper(field.Name[0]) {
+		return ""
+	}
+	return field.Name
+}
+
+// typePackage 带包名的类型所在的包，例如 dto.User -> dto
+func typePackage(qualified string) string {
+	pkg, _, _ := strings.Cut(qualified, ".")
+	return pkg
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,155 @@
+package code
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildOpenAPI(t *testing.T) {
+	src := `package api
+
+import "github.com/gin-gonic/gin"
+
+type Base struct {
+	ID int64 ` + "`json:\"id\"`" + `
+}
+
+type User struct {
+	Base
+	Name  string   ` + "`json:\"name\"`" + `
+	Tags  []string ` + "`json:\"tags,omitempty\"`" + `
+	inner int
+}
+
+func UpdateUser(c *gin.Context) {
+	req := &User{}
+	_ = c.ShouldBindJSON(req)
+	c.JSON(200, req)
+}
+
+func register(r *gin.Engine) {
+	r.PUT("/users/:id", UpdateUser)
+	r.GET("/a", func(c *gin.Context) {})
+	r.GET("/b", func(c *gin.Context) {})
+	r.PUT("/users/id", UpdateUser)
+	r.Any("/any", UpdateUser)
+	r.GET("/a", UpdateUser)
+}
+`
+	path := filepath.Join(t.TempDir(), "api.go")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := NewParser().ParseByFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []*ParseResult{result}
+	doc := BuildOpenAPI("demo", "1.0.0", ResolveEndpointTypes(results), results)
+
+	op := doc.Paths["/users/{id}"]["put"]
+	if op == nil {
+		t.Fatalf("missing operation, paths: %v", doc.Paths)
+	}
+	if len(op.Parameters) != 1 || op.Parameters[0].Name != "id" || op.Parameters[0].In != "path" {
+		t.Errorf("unexpected parameters: %+v", op.Parameters)
+	}
+	if ref := op.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/api.User" {
+		t.Errorf("request schema ref = %s", ref)
+	}
+	// 内联处理函数和挂载在多个路径上的处理函数的 operationId 不重复
+	ids := []string{op.OperationID, doc.Paths["/a"]["get"].OperationID, doc.Paths["/b"]["get"].OperationID, doc.Paths["/users/id"]["put"].OperationID}
+	if want := []string{"put_users_id", "get_a", "get_b", "put_users_id_2"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("operation ids = %v, want %v", ids, want)
+	}
+
+	// ANY 展开为所有方法，路径和方法相同的路由保留先注册的一个并记录警告
+	if len(doc.Paths["/any"]) != 8 {
+		t.Errorf("ANY operations = %d, want 8", len(doc.Paths["/any"]))
+	}
+	if doc.Paths["/a"]["get"].Summary != "<inline>" || len(doc.Warnings) != 1 {
+		t.Errorf("duplicate route: summary = %s, warnings = %v", doc.Paths["/a"]["get"].Summary, doc.Warnings)
+	}
+
+	user := doc.Components.Schemas["api.User"]
+	if len(user.AllOf) != 2 || user.AllOf[0].Ref != "#/components/schemas/api.Base" {
+		t.Fatalf("unexpected user schema: %+v", user)
+	}
+	object := user.AllOf[1]
+	if _, ok := object.Properties["inner"]; ok {
+		t.Errorf("unexported field should be skipped")
+	}
+	if object.Properties["tags"].Items.Type != "string" {
+		t.Errorf("tags schema = %+v", object.Properties["tags"])
+	}
+	if !reflect.DeepEqual(object.Required, []string{"name"}) {
+		t.Errorf("required = %v", object.Required)
+	}
+}
+
+func TestOpenAPISchemaNamesByPackage(t *testing.T) {
+	sources := map[string]string{
+		"user/user.go": `package user
+
+import "github.com/gin-gonic/gin"
+
+type Request struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+func Create(c *gin.Context) {
+	var req Request
+	c.ShouldBindJSON(&req)
+}
+
+func register(r *gin.Engine) {
+	r.POST("/users", Create)
+}
+`,
+		"order/order.go": `package order
+
+import "github.com/gin-gonic/gin"
+
+type Request struct {
+	Amount int ` + "`json:\"amount\"`" + `
+}
+
+func Create(c *gin.Context) {
+	var req Request
+	c.ShouldBindJSON(&req)
+}
+
+func register(r *gin.Engine) {
+	r.POST("/orders", Create)
+}
+`,
+	}
+	root := t.TempDir()
+	var results []*ParseResult
+	for name, src := range sources {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := NewParser().ParseByFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, result)
+	}
+	doc := BuildOpenAPI("demo", "1.0.0", ResolveEndpointTypes(results), results)
+	for path, want := range map[string]string{"/users": "user.Request", "/orders": "order.Request"} {
+		ref := doc.Paths[path]["post"].RequestBody.Content["application/json"].Schema.Ref
+		if ref != "#/components/schemas/"+want {
+			t.Errorf("%s request ref = %s, want %s", path, ref, want)
+		}
+	}
+	if _, ok := doc.Components.Schemas["order.Request"].Properties["amount"]; !ok {
+		t.Errorf("order.Request schema = %+v", doc.Components.Schemas["order.Request"])
+	}
+}
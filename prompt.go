@@ -2,6 +2,9 @@ package code
 
 import "strings"
 
+// PromptVersion 提示词版本，修改分析提示词时递增，记录在总结文件中
+const PromptVersion = "3"
+
 func buildFileAnalysisPrompt(filename, code string) string {
 	p := `请分析以下的代码文件，并提取相关信息。请注意以下要点：
 1. **功能描述**
@@ -114,6 +117,186 @@ file_description: |
 	return strBuilder.String()
 }
 
+func buildTestFileAnalysisPrompt(filename, code string) string {
+	p := `请分析以下的 Golang 测试代码文件，并提取相关信息。请注意以下要点：
+1. **功能描述**
+   - 总结该测试文件覆盖的功能模块。
+
+2. **文件基本信息**
+   - 文件名：
+   - 包名：
+   - 依赖导入项目（列出所有导入的包）：
+
+3. **测试行为**
+   - 列出每个测试函数(TestXxx、BenchmarkXxx、FuzzXxx、ExampleXxx)验证的行为。
+   - 如果是表驱动测试，列出每个用例的名称和期望结果。
+
+4. **测试夹具**
+   - 列出测试依赖的夹具：测试数据文件、mock、辅助函数、setup/teardown 等。
+
+请逐项回答，确保信息清晰明了：
+
+- 输出格式使用**YAML**结构化。
+- 保证输出内容只包含YAML结构，方便后续解析。
+- 输出的描述信息使用中文。
+- 对应字段的值如有混淆，使用单引号包裹。
+- 若某些部分为空，不要输出对应字段。
+
+---
+
+### 输出示例：
+file_description: |
+    <测试XXX功能>
+
+file_info:
+  file_name: <file_name>
+  package_name: <package_name>
+  imports:
+  - <package_1>
+
+tested_behaviors:
+- test: <TestXxx>
+  behavior: <验证的行为>
+  cases:
+  - '<用例名称>: <期望结果>'
+
+fixtures:
+- <夹具描述>
+`
+
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString(p)
+	strBuilder.WriteString("文件名: ")
+	strBuilder.WriteString(filename)
+	strBuilder.WriteString("\n")
+	strBuilder.WriteString("以下是测试代码文件：\n")
+	strBuilder.WriteString(code)
+	return strBuilder.String()
+}
+
+func buildPythonAnalysisPrompt(filename, code string) string {
+	p := `请分析以下的 Python 代码文件，并提取相关信息。请注意以下要点：
+1. **功能描述**
+   - 总结代码文件的整体功能和用途，并列出所有的类和公开函数的名称。
+
+2. **文件基本信息**
+   - 文件名：
+   - 模块名：
+   - 依赖导入项目（列出所有导入的模块）：
+
+3. **类**
+   - 列出所有类及其基类、属性和方法，并简要描述功能。
+
+4. **函数**
+   - 列出所有顶层函数及其参数和返回值，并简要描述功能。
+
+5. **API接口(如果存在)**
+   - 列出 Flask/FastAPI/Django 等框架注册的路由、请求方式、请求参数和响应格式。
+
+请逐项回答，确保信息清晰明了：
+
+- 输出格式使用**YAML**结构化。
+- 保证输出内容只包含YAML结构，方便后续解析。
+- 输出的描述信息使用中文。
+- 对应字段的值如有混淆，使用单引号包裹。
+- 若某些部分为空，不要输出对应字段。
+
+---
+
+### 输出示例：
+file_description: |
+    <文件的功能是实现XXX>
+
+file_info:
+  file_name: <file_name>
+  package_name: <module_name>
+  imports:
+  - <module_1>
+
+classes:
+- name: <class_name>
+  bases:
+  - <base_class>
+  methods:
+  - name: <method_name>
+    params:
+    - <param_1>
+    description: <method_description>
+
+functions:
+- name: <function_name>
+  params:
+  - <param_1>
+  return_values:
+  - <return_type>
+  description: <function_description>
+`
+
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString(p)
+	strBuilder.WriteString("文件名: ")
+	strBuilder.WriteString(filename)
+	strBuilder.WriteString("\n")
+	strBuilder.WriteString("以下是代码文件：\n")
+	strBuilder.WriteString(code)
+	return strBuilder.String()
+}
+
+func buildSQLAnalysisPrompt(filename, code string) string {
+	p := `请分析以下的 SQL 迁移文件，并提取相关信息。请注意以下要点：
+1. **功能描述**
+   - 总结该迁移对数据库结构做了哪些变更，以及可能对应的业务功能。
+
+2. **文件基本信息**
+   - 文件名：
+   - 迁移版本号（取自文件名，没有时为文件名）：
+
+3. **表结构**
+   - 列出新建的表及其字段、类型、主键、索引和外键，并简要描述每个表的用途。
+
+4. **变更**
+   - 列出修改表结构、索引、视图以及数据迁移等语句，区分升级(up)和回滚(down)部分。
+
+请逐项回答，确保信息清晰明了：
+
+- 输出格式使用**YAML**结构化。
+- 保证输出内容只包含YAML结构，方便后续解析。
+- 输出的描述信息使用中文。
+- 对应字段的值如有混淆，使用单引号包裹。
+- 若某些部分为空，不要输出对应字段。
+
+---
+
+### 输出示例：
+file_description: |
+    <迁移的功能是XXX>
+
+file_info:
+  file_name: <file_name>
+  package_name: <version>
+
+tables:
+- name: <table_name>
+  columns:
+  - '<column>: <type>'
+  description: <table_description>
+
+changes:
+- direction: '<up|down>'
+  statement: <statement_summary>
+  description: <change_description>
+`
+
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString(p)
+	strBuilder.WriteString("文件名: ")
+	strBuilder.WriteString(filename)
+	strBuilder.WriteString("\n")
+	strBuilder.WriteString("以下是 SQL 文件：\n")
+	strBuilder.WriteString(code)
+	return strBuilder.String()
+}
+
 func buildQuestionRelFilesPrompt(question, summary string) string {
 	strBuilder := strings.Builder{}
 	strBuilder.WriteString(`你的角色是一个高级开发工程师。根据以下 Golang 源代码中各个文件的总结信息，请回答下面问题。`)
@@ -122,11 +305,14 @@ func buildQuestionRelFilesPrompt(question, summary string) string {
 	输出结果要求:
 	1.只需要列出与该功能相关的文件和选择该文件的依据。
     2.请按照方法的调用层级从低到高输出
-    3.只输出yaml内容
+    3.file 必须与总结信息中的文件名完全一致
+    4.文件的总结信息中列出了符号时，在 symbols 中列出文件中与问题相关的函数、方法(Type.Method)或类型，不确定时留空
+    5.只输出yaml内容
 
 ### 输出示例:
 - file: '<xxx.go>'
   why: '<解释一下为啥选择这个文件>'
+  symbols: ['<Type.Method>', '<Func>']
 
 ### 以下是源码信息:
 	`)
@@ -135,7 +321,98 @@ func buildQuestionRelFilesPrompt(question, summary string) string {
 	return strBuilder.String()
 }
 
-func buildQuestionRelFilesParsePrompt(question, step1Answer, filename, fileContent string) string {
+func buildPackageSummaryPrompt(pkg, fileSummaries string) string {
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString(`你的角色是一个高级开发工程师。以下是包 ` + pkg + ` 中各个文件的总结信息，请总结这个包。
+	### 输出结果要求:
+    1. 说明包的职责、对外提供的主要类型和函数、依赖的其他包
+    2. 不超过 200 个字，使用中文
+    3. 只输出总结内容，不要输出标题和 markdown 代码块
+
+### 以下是文件总结信息:
+`)
+	strBuilder.WriteString(fileSummaries)
+	return strBuilder.String()
+}
+
+func buildArchitecturePrompt(packages string) string {
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString(`你的角色是一个架构师。以下是项目中各个模块和包的总结信息，请生成项目的架构概览。
+	### 输出结果要求:
+    1. 说明项目的用途、主要的分层或模块划分、各部分之间的依赖关系以及核心的处理流程
+    2. 不超过 500 个字，使用中文，可以使用 markdown 列表
+    3. 不要输出标题
+
+### 以下是包总结信息:
+`)
+	strBuilder.WriteString(packages)
+	return strBuilder.String()
+}
+
+func buildQuestionRelPackagesPrompt(question, overview, packages string) string {
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString(`你的角色是一个高级开发工程师。根据以下项目的架构概览和各个包的总结信息，找出回答下面问题需要查看的包。`)
+	strBuilder.WriteString(question)
+	strBuilder.WriteString(`
+	输出结果要求:
+	1.只列出与问题相关的包，最多 8 个，包名必须与包总结信息中的包名完全一致
+    2.只输出yaml内容
+
+### 输出示例:
+- package: '<包名>'
+  why: '<解释一下为啥选择这个包>'
+
+### 架构概览:
+`)
+	strBuilder.WriteString(overview)
+	strBuilder.WriteString("\n\n### 包总结信息:\n")
+	strBuilder.WriteString(packages)
+	return strBuilder.String()
+}
+
+func buildChatRelFilesPrompt(question, history string, analyzedFiles []string, summary string) string {
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString(`你的角色是一个高级开发工程师。我们正在就一个 Golang 项目进行多轮对话，根据以下各个文件的总结信息，找出回答当前问题还需要查看的文件。当前问题:`)
+	strBuilder.WriteString(question)
+	strBuilder.WriteString(`
+	输出结果要求:
+	1.只列出与当前问题相关、且不在已分析文件中的文件，已分析的文件足够回答时输出空列表 []
+    2.file 必须与总结信息中的文件名完全一致
+    3.文件的总结信息中列出了符号时，在 symbols 中列出文件中与问题相关的函数、方法(Type.Method)或类型，不确定时留空
+    4.只输出yaml内容
+
+### 输出示例:
+- file: '<xxx.go>'
+  why: '<解释一下为啥选择这个文件>'
+  symbols: ['<Type.Method>', '<Func>']
+
+### 之前的问题:
+`)
+	strBuilder.WriteString(history)
+	strBuilder.WriteString("\n### 已分析的文件:\n")
+	for _, file := range analyzedFiles {
+		strBuilder.WriteString("- " + file + "\n")
+	}
+	strBuilder.WriteString("\n### 以下是源码信息:\n")
+	strBuilder.WriteString(summary)
+	return strBuilder.String()
+}
+
+func buildChatSystemPrompt(helpInfo string) *strings.Builder {
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString(`你的角色是一个高级开发工程师，正在与用户就一个 Golang 项目进行多轮对话。根据下面相关文件的分析结果和源码回答用户的问题。
+	### 回答要求:
+    1. 结合之前的对话回答，需要时输出方法之间的调用关系图
+    2. 每个结论后面用 ` + "`路径:起始行-结束行`" + ` 引用依据的源码，只能使用分析结果和源码中给出的行号，不要编造行号
+    3. 如果问题中是需要实现一个功能,请写出实现的代码逻辑,以及代码放在什么地方合适
+
+	### 以下是相关参考信息:
+`)
+	strBuilder.WriteString(helpInfo)
+	return &strBuilder
+}
+
+func buildQuestionRelFilesParsePrompt(question, step1Answer, filename, fileContent, facts string) string {
 
 	strBuilder := strings.Builder{}
 	{
@@ -145,14 +422,51 @@ func buildQuestionRelFilesParsePrompt(question, step1Answer, filename, fileConte
 	### 输出结果要求:
     1.解释该源码中关键的方法和方法的作用
     2.列出方法的调用关系
+    3.源码每行开头是行号，每个结论后面用 ` + "`" + filename + `:起始行-结束行` + "`" + ` 标注依据的代码行，例如 ` + "`" + filename + `:12-30` + "`" + `
     下面是第一步分析得到的总结信息:`)
 		strBuilder.WriteString(step1Answer)
 		strBuilder.WriteString("\n\n")
 		strBuilder.WriteString(`
-	### 以下是 ` + filename + `文件源码信息：
+	### 以下是 ` + filename + `文件源码信息(较大的文件只包含相关函数的源码、调用关系和其他声明的签名)：
 	`)
 
 		strBuilder.WriteString(string(fileContent))
+
+		if facts != "" {
+			strBuilder.WriteString(`
+	### 以下是静态分析得到的函数信息(calls: 调用的函数, reads/writes: 读写的结构体字段, errors: 返回的错误)：
+	`)
+			strBuilder.WriteString(facts)
+		}
+	}
+	return strBuilder.String()
+}
+
+func buildOperationDocPrompt(method, path string, ep *Endpoint, facts *FuncFacts) string {
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString(`你的角色是一个高级开发工程师。请根据以下 HTTP 接口的静态分析信息，为 OpenAPI 文档生成接口的摘要和描述。
+	### 输出结果要求:
+    1. summary 不超过 20 个字
+    2. description 说明接口的用途、主要处理逻辑和可能返回的错误
+    3. 输出的描述信息使用中文，只输出yaml内容
+
+### 输出示例:
+summary: '<接口摘要>'
+description: '<接口描述>'
+
+### 以下是接口信息:
+`)
+	strBuilder.WriteString("请求: " + strings.ToUpper(method) + " " + path + "\n")
+	strBuilder.WriteString("处理函数: " + ep.Handler + " (" + ep.File + ")\n")
+	if ep.RequestType != "" {
+		strBuilder.WriteString("请求结构体: " + ep.RequestType + "\n")
+	}
+	if ep.ResponseType != "" {
+		strBuilder.WriteString("响应结构体: " + ep.ResponseType + "\n")
+	}
+	if facts != nil {
+		strBuilder.WriteString("处理函数调用的函数: " + strings.Join(facts.Calls, ", ") + "\n")
+		strBuilder.WriteString("处理函数返回的错误: " + strings.Join(facts.Errors, ", ") + "\n")
 	}
 	return strBuilder.String()
 }
@@ -180,6 +494,7 @@ func buildFinalAnswerPrompt(question, helpInfo string) *strings.Builder {
 		  +---> <xx>.go
     2. 总结功能实现的逻辑
     3. 如果问题中是需要实现一个功能,请写出实现的代码逻辑,以及代码放在什么地方合适
+    4. 每个结论后面用 ` + "`路径:起始行-结束行`" + ` 引用依据的源码，只能使用下面文件分析结果中给出的引用，不要编造行号
 `)
 	strBuilder3.WriteString(`
 	### 以下是相关参考信息:
@@ -0,0 +1,419 @@
+package code
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ProtoFile .proto 文件的解析结果
+type ProtoFile struct {
+	File      string          `json:"file" yaml:"file"`
+	Package   string          `json:"package" yaml:"package"`
+	GoPackage string          `json:"go_package,omitempty" yaml:"go_package,omitempty"`
+	Imports   []string        `json:"imports,omitempty" yaml:"imports,omitempty"`
+	Services  []*ProtoService `json:"services,omitempty" yaml:"services,omitempty"`
+	Messages  []*ProtoMessage `json:"messages,omitempty" yaml:"messages,omitempty"`
+	Enums     []string        `json:"enums,omitempty" yaml:"enums,omitempty"`
+}
+
+// ProtoService gRPC 服务定义
+type ProtoService struct {
+	Name string      `json:"name" yaml:"name"`
+	RPCs []*ProtoRPC `json:"rpcs" yaml:"rpcs"`
+}
+
+// ProtoRPC 服务中的 RPC 方法
+type ProtoRPC struct {
+	Name            string `json:"name" yaml:"name"`
+	Request         string `json:"request" yaml:"request"`
+	Response        string `json:"response" yaml:"response"`
+	ClientStreaming bool   `json:"client_streaming,omitempty" yaml:"client_streaming,omitempty"`
+	ServerStreaming bool   `json:"server_streaming,omitempty" yaml:"server_streaming,omitempty"`
+}
+
+// String 返回 Name(Req) returns (Resp) 形式的描述
+func (r *ProtoRPC) String() string {
+	req, resp := r.Request, r.Response
+	if r.ClientStreaming {
+		req = "stream " + req
+	}
+	if r.ServerStreaming {
+		resp = "stream " + resp
+	}
+	return fmt.Sprintf("%s(%s) returns (%s)", r.Name, req, resp)
+}
+
+// ProtoMessage 消息定义，嵌套消息的名称为 Outer.Inner
+type ProtoMessage struct {
+	Name   string   `json:"name" yaml:"name"`
+	Fields []string `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// GoName 生成代码中对应的 Go 结构体名称，例如 Outer.Inner -> Outer_Inner
+func (m *ProtoMessage) GoName() string {
+	return strings.ReplaceAll(m.Name, ".", "_")
+}
+
+// IsProtoGeneratedFile 判断是否是 protoc 生成的 Go 文件
+func IsProtoGeneratedFile(path string) bool {
+	return strings.HasSuffix(path, ".pb.go") || strings.HasSuffix(path, ".pb.gw.go")
+}
+
+// ParseProtoFile 解析 .proto 文件中的服务、RPC 和消息
+func ParseProtoFile(filePath string) (*ProtoFile, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	p := &protoParser{tokens: tokenizeProto(string(content))}
+	file := &ProtoFile{File: filePath}
+	if err := p.parseFile(file); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", filePath, err)
+	}
+	return file, nil
+}
+
+type protoParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *protoParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	return tok
+}
+
+func (p *protoParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *protoParser) expect(want string) error {
+	if tok := p.next(); tok != want {
+		return fmt.Errorf("expected %q, got %q", want, tok)
+	}
+	return nil
+}
+
+// skipStatement 跳过到分号或与之匹配的右花括号
+func (p *protoParser) skipStatement() {
+	depth := 0
+	for tok := p.next(); tok != ""; tok = p.next() {
+		switch tok {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth <= 0 {
+				return
+			}
+		case ";":
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+func (p *protoParser) parseFile(file *ProtoFile) error {
+	for p.peek() != "" {
+		switch tok := p.next(); tok {
+		case "package":
+			file.Package = p.next()
+			p.skipStatement()
+		case "import":
+			name := p.next()
+			if name == "public" || name == "weak" {
+				name = p.next()
+			}
+			file.Imports = append(file.Imports, strings.Trim(name, `"`))
+			p.skipStatement()
+		case "option":
+			name := p.next()
+			if name == "go_package" && p.next() == "=" {
+				file.GoPackage = strings.Trim(p.next(), `"`)
+			}
+			p.skipStatement()
+		case "message":
+			if err := p.parseMessage("", file); err != nil {
+				return err
+			}
+		case "enum":
+			file.Enums = append(file.Enums, p.next())
+			p.skipStatement()
+		case "service":
+			service, err := p.parseService()
+			if err != nil {
+				return err
+			}
+			file.Services = append(file.Services, service)
+		case ";":
+		default:
+			p.skipStatement()
+		}
+	}
+	return nil
+}
+
+func (p *protoParser) parseMessage(parent string, file *ProtoFile) error {
+	name := p.next()
+	if parent != "" {
+		name = parent + "." + name
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	message := &ProtoMessage{Name: name}
+	file.Messages = append(file.Messages, message)
+
+	for {
+		switch tok := p.peek(); tok {
+		case "":
+			return fmt.Errorf("unexpected end of message %s", name)
+		case "}":
+			p.next()
+			return nil
+		case "message":
+			p.next()
+			if err := p.parseMessage(name, file); err != nil {
+				return err
+			}
+		case "enum":
+			p.next()
+			file.Enums = append(file.Enums, name+"."+p.next())
+			p.skipStatement()
+		case "oneof":
+			// oneof 中的字段直接归属于消息
+			p.next()
+			p.next()
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			for p.peek() != "}" && p.peek() != "" {
+				p.parseField(message)
+			}
+			p.next()
+		case "option", "reserved", "extensions", "extend", ";":
+			p.skipStatement()
+		default:
+			p.parseField(message)
+		}
+	}
+}
+
+// parseField 解析 [repeated|optional] type name = N [options]; 或 map<K, V> name = N;
+func (p *protoParser) parseField(message *ProtoMessage) {
+	var typeParts []string
+	for tok := p.next(); tok != "" && tok != "="; tok = p.next() {
+		if tok == ";" || tok == "{" {
+			// 无法识别的语句
+			if tok == "{" {
+				p.pos--
+				p.skipStatement()
+			}
+			return
+		}
+		typeParts = append(typeParts, tok)
+	}
+	if len(typeParts) < 2 {
+		p.skipStatement()
+		return
+	}
+	fieldName := typeParts[len(typeParts)-1]
+	fieldType := strings.Join(typeParts[:len(typeParts)-1], " ")
+	fieldType = strings.NewReplacer(" < ", "<", " , ", ", ", " >", ">").Replace(fieldType)
+	message.Fields = append(message.Fields, fieldName+": "+fieldType)
+	p.skipStatement()
+}
+
+func (p *protoParser) parseService() (*ProtoService, error) {
+	service := &ProtoService{Name: p.next()}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for {
+		switch tok := p.next(); tok {
+		case "":
+			return nil, fmt.Errorf("unexpected end of service %s", service.Name)
+		case "}":
+			return service, nil
+		case "rpc":
+			rpc := &ProtoRPC{Name: p.next()}
+			rpc.Request, rpc.ClientStreaming = p.parseRPCType()
+			if err := p.expect("returns"); err != nil {
+				return nil, err
+			}
+			rpc.Response, rpc.ServerStreaming = p.parseRPCType()
+			service.RPCs = append(service.RPCs, rpc)
+			if p.peek() == "{" {
+				p.skipStatement()
+			} else if p.peek() == ";" {
+				p.next()
+			}
+		default:
+			p.skipStatement()
+		}
+	}
+}
+
+// parseRPCType 解析 (stream Type) 形式的请求/响应类型
+func (p *protoParser) parseRPCType() (string, bool) {
+	if p.peek() != "(" {
+		return "", false
+	}
+	p.next()
+	streaming := false
+	name := p.next()
+	if name == "stream" && p.peek() != ")" {
+		streaming = true
+		name = p.next()
+	}
+	for tok := p.next(); tok != ")" && tok != ""; tok = p.next() {
+	}
+	return name, streaming
+}
+
+// tokenizeProto 切分 .proto 源码，去掉注释
+func tokenizeProto(src string) []string {
+	var tokens []string
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(runes) && runes[j] != c {
+				if runes[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(runes))
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.' || c == '-' || c == '+':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || strings.ContainsRune("_.-+", runes[j])) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+// ProtoServiceLink gRPC 服务与生成代码、服务实现之间的关联
+type ProtoServiceLink struct {
+	Proto           string   `json:"proto" yaml:"proto"`
+	Service         string   `json:"service" yaml:"service"`
+	RPCs            []string `json:"rpcs" yaml:"rpcs"`
+	GoInterface     string   `json:"go_interface,omitempty" yaml:"go_interface,omitempty"`
+	GeneratedFile   string   `json:"generated_file,omitempty" yaml:"generated_file,omitempty"`
+	Implementations []string `json:"implementations,omitempty" yaml:"implementations,omitempty"`
+}
+
+// ProtoMessageLink 消息与生成的 Go 结构体之间的关联
+type ProtoMessageLink struct {
+	Proto         string `json:"proto" yaml:"proto"`
+	Message       string `json:"message" yaml:"message"`
+	GoType        string `json:"go_type,omitempty" yaml:"go_type,omitempty"`
+	GeneratedFile string `json:"generated_file,omitempty" yaml:"generated_file,omitempty"`
+}
+
+// ProtoLinks .proto 定义与 Go 代码的关联关系
+type ProtoLinks struct {
+	Services []*ProtoServiceLink `json:"services,omitempty" yaml:"services,omitempty"`
+	Messages []*ProtoMessageLink `json:"messages,omitempty" yaml:"messages,omitempty"`
+}
+
+// LinkProto 把 .proto 中的服务和消息关联到生成的 Go 类型和手写的服务实现
+func LinkProto(protos []*ProtoFile, results []*ParseResult) *ProtoLinks {
+	links := &ProtoLinks{}
+	for _, proto := range protos {
+		for _, service := range proto.Services {
+			link := &ProtoServiceLink{Proto: proto.File, Service: service.Name}
+			for _, rpc := range service.RPCs {
+				link.RPCs = append(link.RPCs, rpc.String())
+			}
+			serverInterface := service.Name + "Server"
+			for _, result := range results {
+				if _, ok := result.Interfaces[serverInterface]; ok && IsProtoGeneratedFile(result.FilePath) {
+					link.GoInterface = result.PackageName + "." + serverInterface
+					link.GeneratedFile = result.FilePath
+				}
+				if IsProtoGeneratedFile(result.FilePath) {
+					continue
+				}
+				for _, name := range sortedKeys(result.Structs) {
+					if implementsProtoService(result.Structs[name], service) {
+						link.Implementations = append(link.Implementations, result.FilePath+":"+name)
+					}
+				}
+			}
+			links.Services = append(links.Services, link)
+		}
+
+		for _, message := range proto.Messages {
+			link := &ProtoMessageLink{Proto: proto.File, Message: message.Name}
+			for _, result := range results {
+				if _, ok := result.Structs[message.GoName()]; ok && IsProtoGeneratedFile(result.FilePath) {
+					link.GoType = result.PackageName + "." + message.GoName()
+					link.GeneratedFile = result.FilePath
+					break
+				}
+			}
+			links.Messages = append(links.Messages, link)
+		}
+	}
+	return links
+}
+
+// implementsProtoService 嵌入了 Unimplemented<Service>Server 或者实现了所有 RPC 方法的结构体视为服务实现
+func implementsProtoService(info *StructInfo, service *ProtoService) bool {
+	unimplemented := "Unimplemented" + service.Name + "Server"
+	for _, field := range info.FieldDefs {
+		if field.Embedded && (field.Name == unimplemented || strings.HasSuffix(field.Name, "."+unimplemented)) {
+			return true
+		}
+	}
+	if len(service.RPCs) == 0 {
+		return false
+	}
+	for _, rpc := range service.RPCs {
+		found := false
+		for _, method := range info.Methods {
+			if strings.HasPrefix(method, rpc.Name+"(") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
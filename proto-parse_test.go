@@ -0,0 +1,95 @@
+package code
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseProtoAndLink(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"greeter.proto": `syntax = "proto3";
+package demo.v1;
+option go_package = "example.com/demo/pb";
+import "google/protobuf/empty.proto";
+
+// Greeter 服务
+service Greeter {
+  rpc SayHello (HelloRequest) returns (HelloReply);
+  rpc Watch (stream HelloRequest) returns (stream HelloReply) { option deprecated = true; }
+}
+
+message HelloRequest {
+  string name = 1; /* 名称 */
+  map<string, int32> labels = 2;
+  message Meta { repeated string tags = 1; }
+  oneof target { string email = 3; int64 uid = 4; }
+}
+
+message HelloReply { string message = 1 [json_name = "msg"]; }
+`,
+		"greeter.pb.go": `package pb
+
+type HelloRequest struct{ Name string }
+type HelloRequest_Meta struct{ Tags []string }
+type HelloReply struct{ Message string }
+
+type GreeterServer interface {
+	SayHello() error
+}
+
+type UnimplementedGreeterServer struct{}
+`,
+		"server.go": `package server
+
+import "example.com/demo/pb"
+
+type greeter struct {
+	pb.UnimplementedGreeterServer
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	proto, err := ParseProtoFile(filepath.Join(dir, "greeter.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proto.Package != "demo.v1" || proto.GoPackage != "example.com/demo/pb" {
+		t.Errorf("package = %s, go_package = %s", proto.Package, proto.GoPackage)
+	}
+	rpcs := proto.Services[0].RPCs
+	if len(rpcs) != 2 || rpcs[1].String() != "Watch(stream HelloRequest) returns (stream HelloReply)" {
+		t.Errorf("unexpected rpcs: %v", rpcs)
+	}
+	wantFields := []string{"name: string", "labels: map<string, int32>", "email: string", "uid: int64"}
+	if !reflect.DeepEqual(proto.Messages[0].Fields, wantFields) {
+		t.Errorf("fields = %v, want %v", proto.Messages[0].Fields, wantFields)
+	}
+	if len(proto.Messages) != 3 || proto.Messages[1].GoName() != "HelloRequest_Meta" {
+		t.Errorf("unexpected messages: %v", proto.Messages)
+	}
+
+	var results []*ParseResult
+	for _, name := range []string{"greeter.pb.go", "server.go"} {
+		result, err := NewParser().ParseByFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, result)
+	}
+	links := LinkProto([]*ProtoFile{proto}, results)
+	service := links.Services[0]
+	if service.GoInterface != "pb.GreeterServer" || len(service.Implementations) != 1 {
+		t.Errorf("unexpected service link: %+v", service)
+	}
+	if links.Messages[1].GoType != "pb.HelloRequest_Meta" {
+		t.Errorf("unexpected message link: %+v", links.Messages[1])
+	}
+}
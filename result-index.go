@@ -0,0 +1,68 @@
+package code
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ResultIndexFileName 输出目录中的结果索引文件
+const ResultIndexFileName = "index.yaml"
+
+// ResultIndex 源文件与分析结果文件的对应关系，路径均相对于各自的根目录并使用 / 分隔
+type ResultIndex struct {
+	// Root 被分析目录的绝对路径
+	Root string `yaml:"root"`
+	// Files 源文件相对路径 -> 结果文件相对于输出目录的路径
+	Files map[string]string `yaml:"files"`
+}
+
+// LoadResultIndex 读取结果索引，文件不存在时返回空索引
+func LoadResultIndex(path string) (*ResultIndex, error) {
+	index := &ResultIndex{Files: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, err
+	}
+	if index.Files == nil {
+		index.Files = make(map[string]string)
+	}
+	return index, nil
+}
+
+// Set 记录源文件的结果文件
+func (i *ResultIndex) Set(source, result string) {
+	i.Files[filepath.ToSlash(source)] = filepath.ToSlash(result)
+}
+
+// Remove 删除源文件的记录
+func (i *ResultIndex) Remove(source string) {
+	delete(i.Files, filepath.ToSlash(source))
+}
+
+// Sources 按路径排序的源文件列表
+func (i *ResultIndex) Sources() []string {
+	sources := make([]string, 0, len(i.Files))
+	for source := range i.Files {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// Save 写入结果索引
+func (i *ResultIndex) Save(path string) error {
+	data, err := yaml.Marshal(i)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
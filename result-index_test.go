@@ -0,0 +1,37 @@
+package code
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResultIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ResultIndexFileName)
+	index, err := LoadResultIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.Root = "/src/project"
+	index.Set("pkg/b.go", "pkg/b.go.yaml")
+	index.Set("a.go", "a.go.yaml")
+	index.Set("pkg/old.go", "pkg/old.go.yaml")
+	index.Remove("pkg/old.go")
+	if err := index.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadResultIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Root != "/src/project" {
+		t.Errorf("root = %s", loaded.Root)
+	}
+	if want := []string{"a.go", "pkg/b.go"}; !reflect.DeepEqual(loaded.Sources(), want) {
+		t.Errorf("sources = %v, want %v", loaded.Sources(), want)
+	}
+	if loaded.Files["pkg/b.go"] != "pkg/b.go.yaml" {
+		t.Errorf("result of pkg/b.go = %s", loaded.Files["pkg/b.go"])
+	}
+}
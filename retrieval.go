@@ -0,0 +1,129 @@
+package code
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Retriever 组合关键词索引和向量索引检索相关的文件和符号，两者都可以为空
+type Retriever struct {
+	Keyword *SearchIndex
+	Vectors VectorStore
+	// Embedder 为空时不使用向量索引
+	Embedder EmbeddingProvider
+}
+
+// RetrievedSymbol 检索到的符号
+type RetrievedSymbol struct {
+	Path  string
+	Name  string
+	Score float64
+}
+
+// Retrieval 检索结果，均按相关性从高到低排序
+type Retrieval struct {
+	Files    []string
+	Symbols  []*RetrievedSymbol
+	Packages []string
+	// Semantic 是否使用了向量检索
+	Semantic bool
+}
+
+// defaultSymbolLimit 不限制数量时最多返回的符号和包数量，符号用于提示相关的函数，数量过多没有意义
+const defaultSymbolLimit = 20
+
+// Retrieve 检索与查询相关的文件、符号和包，limit <= 0 时返回所有文件以及最多 defaultSymbolLimit 个符号和包
+//
+// 关键词和向量两路的文件排序使用倒数排名融合合并，向量检索失败时只使用关键词检索并返回错误
+func (r *Retriever) Retrieve(query string, limit int) (*Retrieval, error) {
+	retrieval := &Retrieval{}
+	symbolLimit := limit
+	if symbolLimit <= 0 {
+		symbolLimit = defaultSymbolLimit
+	}
+	var keywordFiles []string
+	if r.Keyword != nil {
+		for _, hit := range r.Keyword.Search(query, 0) {
+			keywordFiles = append(keywordFiles, hit.Path)
+		}
+	}
+
+	var err error
+	var vectorFiles []string
+	if r.Vectors != nil && r.Embedder != nil {
+		var vectors [][]float32
+		vectors, err = r.Embedder.Embed([]string{query})
+		if err == nil && len(vectors) != 1 {
+			err = fmt.Errorf("embedding provider returned %d vectors for 1 text", len(vectors))
+		}
+		if err == nil {
+			retrieval.Semantic = true
+			vector := vectors[0]
+			for _, hit := range r.Vectors.Search(vector, limit, VectorKindFile) {
+				vectorFiles = append(vectorFiles, hit.Path)
+			}
+			for _, hit := range r.Vectors.Search(vector, symbolLimit, VectorKindSymbol) {
+				retrieval.Symbols = append(retrieval.Symbols, &RetrievedSymbol{Path: hit.Path, Name: hit.Name, Score: hit.Score})
+				vectorFiles = appendUnique(vectorFiles, hit.Path)
+			}
+			for _, hit := range r.Vectors.Search(vector, symbolLimit, VectorKindPackage) {
+				retrieval.Packages = append(retrieval.Packages, hit.Name)
+			}
+		}
+	}
+
+	retrieval.Files = FuseRankings(keywordFiles, vectorFiles)
+	if limit > 0 && len(retrieval.Files) > limit {
+		retrieval.Files = retrieval.Files[:limit]
+	}
+	if !retrieval.Semantic && r.Keyword != nil {
+		retrieval.Symbols = r.Keyword.matchSymbols(query, retrieval.Files, symbolLimit)
+	}
+	return retrieval, err
+}
+
+// matchSymbols 在文件中查找名称包含查询词的符号，得分为命中的词数
+func (idx *SearchIndex) matchSymbols(query string, files []string, limit int) []*RetrievedSymbol {
+	terms := make(map[string]bool)
+	for _, term := range SearchTokens(query) {
+		terms[term] = true
+	}
+	docs := make(map[string]*SearchDoc, len(idx.Docs))
+	for _, doc := range idx.Docs {
+		docs[doc.Path] = doc
+	}
+
+	var symbols []*RetrievedSymbol
+	for _, file := range files {
+		doc := docs[file]
+		if doc == nil {
+			continue
+		}
+		for _, symbol := range doc.Symbols {
+			score := 0
+			for _, term := range SearchTokens(symbol) {
+				if terms[term] {
+					score++
+				}
+			}
+			if score > 0 {
+				symbols = append(symbols, &RetrievedSymbol{Path: file, Name: symbol, Score: float64(score)})
+			}
+		}
+	}
+	// 同分时保持文件的排序
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return symbols[i].Score > symbols[j].Score
+	})
+	if limit > 0 && len(symbols) > limit {
+		symbols = symbols[:limit]
+	}
+	return symbols
+}
+
+func appendUnique(values []string, value string) []string {
+	if containsString(values, value) {
+		return values
+	}
+	return append(values, value)
+}
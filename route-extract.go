@@ -0,0 +1,636 @@
+package code
+
+import (
+	"go/ast"
+	"go/token"
+	"maps"
+	pathpkg "path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Endpoint 静态分析得到的 HTTP 路由
+type Endpoint struct {
+	Method  string `json:"method" yaml:"method"`
+	Path    string `json:"path" yaml:"path"`
+	Handler string `json:"handler" yaml:"handler"`
+	// HandlerKey 带包名和接收者类型的处理函数，例如 api.UserHandler.Create，无法确定时为空
+	HandlerKey   string `json:"handler_key,omitempty" yaml:"handler_key,omitempty"`
+	Router       string `json:"router" yaml:"router"`
+	RequestType  string `json:"request_type,omitempty" yaml:"request_type,omitempty"`
+	ResponseType string `json:"response_type,omitempty" yaml:"response_type,omitempty"`
+	File         string `json:"file" yaml:"file"`
+	Line         int    `json:"line" yaml:"line"`
+}
+
+// String 返回 GET /path -> handler 形式的描述
+func (e *Endpoint) String() string {
+	return e.Method + " " + e.Path + " -> " + e.Handler
+}
+
+// HandlerIO 处理函数中绑定的请求结构体和返回的响应结构体
+type HandlerIO struct {
+	RequestType  string `json:"request_type,omitempty" yaml:"request_type,omitempty"`
+	ResponseType string `json:"response_type,omitempty" yaml:"response_type,omitempty"`
+}
+
+// 支持的路由框架
+const (
+	RouterNetHTTP = "net/http"
+	RouterGin     = "gin"
+	RouterEcho    = "echo"
+	RouterChi     = "chi"
+	RouterMux     = "gorilla/mux"
+)
+
+var routerImports = map[string]string{
+	"net/http":                    RouterNetHTTP,
+	"github.com/gin-gonic/gin":    RouterGin,
+	"github.com/labstack/echo":    RouterEcho,
+	"github.com/labstack/echo/v4": RouterEcho,
+	"github.com/go-chi/chi":       RouterChi,
+	"github.com/go-chi/chi/v5":    RouterChi,
+	"github.com/gorilla/mux":      RouterMux,
+}
+
+// gin/echo 使用大写的方法名，chi 使用首字母大写的方法名
+var upperMethods = map[string]string{
+	"GET": "GET", "POST": "POST", "PUT": "PUT", "DELETE": "DELETE",
+	"PATCH": "PATCH", "HEAD": "HEAD", "OPTIONS": "OPTIONS", "Any": "ANY",
+}
+
+var chiMethods = map[string]string{
+	"Get": "GET", "Post": "POST", "Put": "PUT", "Delete": "DELETE",
+	"Patch": "PATCH", "Head": "HEAD", "Options": "OPTIONS", "Connect": "CONNECT", "Trace": "TRACE",
+}
+
+// 请求绑定方法，参数为请求结构体指针
+var bindMethods = map[string]bool{
+	"Bind": true, "BindJSON": true, "ShouldBind": true, "ShouldBindJSON": true,
+	"ShouldBindQuery": true, "BindQuery": true, "ShouldBindUri": true, "Decode": true,
+}
+
+// 响应方法，最后一个参数为响应结构体
+var respondMethods = map[string]bool{
+	"JSON": true, "IndentedJSON": true, "PureJSON": true, "XML": true, "Encode": true,
+}
+
+// routeExtractor 在单个文件中识别路由注册
+type routeExtractor struct {
+	fset     *token.FileSet
+	filePath string
+	pkg      string
+	routers  map[string]bool
+	// imports 导入包的名称 -> 包名，用于识别 users.Create 形式的处理函数
+	imports map[string]string
+	// funcResults 文件中函数名 -> 第一个返回值的类型，用于推断 h := NewUserHandler() 的类型
+	funcResults map[string]string
+	// fields 文件中结构体名 -> 字段名 -> 字段类型
+	fields map[string]map[string]string
+	// varTypes 当前函数中变量 -> 类型
+	varTypes map[string]string
+	// globals 包级变量 -> 类型
+	globals map[string]string
+	// netHTTP 文件中 net/http 包的名称，未导入时为空
+	netHTTP string
+	// scopes chi Route 回调函数的参数 -> 路径前缀
+	scopes    map[*ast.FuncLit]map[string]string
+	visited   map[*ast.CallExpr]bool
+	endpoints []*Endpoint
+}
+
+// extractEndpoints 识别 net/http、gin、echo、chi 和 gorilla/mux 的路由注册
+func extractEndpoints(fset *token.FileSet, filePath string, f *ast.File) []*Endpoint {
+	e := &routeExtractor{
+		fset:        fset,
+		filePath:    filePath,
+		pkg:         f.Name.Name,
+		routers:     make(map[string]bool),
+		imports:     make(map[string]string),
+		funcResults: make(map[string]string),
+		fields:      make(map[string]map[string]string),
+		globals:     make(map[string]string),
+		scopes:      make(map[*ast.FuncLit]map[string]string),
+		visited:     make(map[*ast.CallExpr]bool),
+	}
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		if router, ok := routerImports[path]; ok {
+			e.routers[router] = true
+		}
+		name := pathpkg.Base(path)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		e.imports[name] = pathpkg.Base(path)
+		if path == "net/http" {
+			e.netHTTP = name
+		}
+	}
+	if len(e.routers) == 0 {
+		return nil
+	}
+	e.collectTypes(f)
+
+	// 路由分组前缀和变量类型只在声明它们的函数内有效
+	for _, decl := range f.Decls {
+		e.varTypes = make(map[string]string)
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			e.collectVarTypes(fn)
+		}
+		e.inspect(decl, make(map[string]string))
+	}
+
+	handlers := extractHandlerIO(f)
+	for _, ep := range e.endpoints {
+		if io, ok := handlers[ep.HandlerKey]; ok {
+			ep.RequestType = io.RequestType
+			ep.ResponseType = io.ResponseType
+		}
+	}
+	return e.endpoints
+}
+
+// inspect 遍历节点中的路由注册，prefixes 为当前作用域中路由分组变量 -> 路径前缀，进入函数字面量时使用副本
+func (e *routeExtractor) inspect(node ast.Node, prefixes map[string]string) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.FuncLit:
+			scope := maps.Clone(prefixes)
+			for name, prefix := range e.scopes[t] {
+				scope[name] = prefix
+			}
+			e.inspect(t.Body, scope)
+			return false
+		case *ast.AssignStmt:
+			e.trackGroup(t, prefixes)
+		case *ast.CallExpr:
+			e.inspectCall(t, prefixes)
+		}
+		return true
+	})
+}
+
+// collectTypes 记录文件中函数的返回类型、结构体的字段类型和包级变量的类型
+func (e *routeExtractor) collectTypes(f *ast.File) {
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Type.Results != nil && len(d.Type.Results.List) > 0 {
+				e.funcResults[d.Name.Name] = exprToString(d.Type.Results.List[0].Type)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				fields := make(map[string]string)
+				for _, field := range st.Fields.List {
+					for _, name := range field.Names {
+						fields[name.Name] = exprToString(field.Type)
+					}
+				}
+				e.fields[ts.Name.Name] = fields
+			}
+		}
+	}
+	// 包级变量的初始值可能调用文件中的函数，需要在记录函数返回类型之后推断
+	for _, decl := range f.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.VAR {
+			for _, spec := range d.Specs {
+				e.valueSpecTypes(spec.(*ast.ValueSpec), e.globals)
+			}
+		}
+	}
+}
+
+// collectVarTypes 记录函数的接收者、参数和局部变量的类型
+func (e *routeExtractor) collectVarTypes(fn *ast.FuncDecl) {
+	for _, list := range []*ast.FieldList{fn.Recv, fn.Type.Params} {
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			for _, name := range field.Names {
+				e.varTypes[name.Name] = exprToString(field.Type)
+			}
+		}
+	}
+	if fn.Body == nil {
+		return
+	}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.ValueSpec:
+			e.valueSpecTypes(t, e.varTypes)
+		case *ast.AssignStmt:
+			for i, lhs := range t.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && i < len(t.Rhs) {
+					if typeName := e.exprType(t.Rhs[i]); typeName != "" {
+						e.varTypes[ident.Name] = typeName
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+// valueSpecTypes 把 var 声明中变量的类型记录到 types
+func (e *routeExtractor) valueSpecTypes(spec *ast.ValueSpec, types map[string]string) {
+	for i, name := range spec.Names {
+		if spec.Type != nil {
+			types[name.Name] = exprToString(spec.Type)
+		} else if i < len(spec.Values) {
+			if typeName := e.exprType(spec.Values[i]); typeName != "" {
+				types[name.Name] = typeName
+			}
+		}
+	}
+}
+
+// exprType 推断 T{}、&T{}、new(T)、NewT()、http.NewServeMux() 以及变量和字段表达式的类型
+func (e *routeExtractor) exprType(expr ast.Expr) string {
+	if typeName := literalType(expr); typeName != "" {
+		return typeName
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if typeName, ok := e.varTypes[t.Name]; ok {
+			return typeName
+		}
+		return e.globals[t.Name]
+	case *ast.CallExpr:
+		switch fn := t.Fun.(type) {
+		case *ast.Ident:
+			return e.funcResults[fn.Name]
+		case *ast.SelectorExpr:
+			if ident, ok := fn.X.(*ast.Ident); ok && e.netHTTP != "" && ident.Name == e.netHTTP && fn.Sel.Name == "NewServeMux" {
+				return "*" + e.netHTTP + ".ServeMux"
+			}
+		}
+	case *ast.SelectorExpr:
+		owner := receiverTypeName(e.exprType(t.X))
+		return e.fields[owner][t.Sel.Name]
+	}
+	return ""
+}
+
+// resolveHandler 把处理函数表达式解析为 HandlerKey：
+// CreateUser -> api.CreateUser，users.Create(导入的包) -> users.Create，h.Create -> api.UserHandler.Create
+func (e *routeExtractor) resolveHandler(handler ast.Expr) string {
+	switch t := handler.(type) {
+	case *ast.Ident:
+		return e.pkg + "." + t.Name
+	case *ast.SelectorExpr:
+		if ident, ok := t.X.(*ast.Ident); ok && e.varTypes[ident.Name] == "" {
+			if pkg, ok := e.imports[ident.Name]; ok {
+				return pkg + "." + t.Sel.Name
+			}
+		}
+		typeName := receiverTypeName(e.exprType(t.X))
+		if typeName == "" {
+			return ""
+		}
+		if !strings.Contains(typeName, ".") {
+			typeName = e.pkg + "." + typeName
+		} else if pkg, rest, _ := strings.Cut(typeName, "."); e.imports[pkg] != "" {
+			typeName = e.imports[pkg] + "." + rest
+		}
+		return typeName + "." + t.Sel.Name
+	}
+	return ""
+}
+
+// trackGroup 记录 v1 := r.Group("/v1") 或 s := r.PathPrefix("/api").Subrouter() 形式的路由分组
+func (e *routeExtractor) trackGroup(assign *ast.AssignStmt, prefixes map[string]string) {
+	if len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return
+	}
+	ident, ok := assign.Lhs[0].(*ast.Ident)
+	if !ok {
+		return
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+
+	switch sel.Sel.Name {
+	case "Group":
+		if len(call.Args) > 0 {
+			if path, ok := stringLit(call.Args[0]); ok {
+				prefixes[ident.Name] = prefixOf(prefixes, sel.X) + path
+			}
+		}
+	case "Subrouter":
+		if inner, ok := sel.X.(*ast.CallExpr); ok {
+			if innerSel, ok := inner.Fun.(*ast.SelectorExpr); ok && innerSel.Sel.Name == "PathPrefix" && len(inner.Args) > 0 {
+				if path, ok := stringLit(inner.Args[0]); ok {
+					prefixes[ident.Name] = prefixOf(prefixes, innerSel.X) + path
+				}
+			}
+		}
+	}
+}
+
+func prefixOf(prefixes map[string]string, expr ast.Expr) string {
+	if ident, ok := expr.(*ast.Ident); ok {
+		return prefixes[ident.Name]
+	}
+	return ""
+}
+
+func (e *routeExtractor) inspectCall(call *ast.CallExpr, prefixes map[string]string) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || e.visited[call] {
+		return
+	}
+	e.visited[call] = true
+	name := sel.Sel.Name
+	prefix := prefixOf(prefixes, sel.X)
+
+	switch {
+	case name == "Route" && e.routers[RouterChi] && len(call.Args) == 2:
+		// chi: r.Route("/users", func(r chi.Router) { ... })
+		path, ok := stringLit(call.Args[0])
+		fn, isFunc := call.Args[1].(*ast.FuncLit)
+		if ok && isFunc && len(fn.Type.Params.List) > 0 && len(fn.Type.Params.List[0].Names) > 0 {
+			e.scopes[fn] = map[string]string{fn.Type.Params.List[0].Names[0].Name: prefix + path}
+		}
+	case upperMethods[name] != "" && (e.routers[RouterGin] || e.routers[RouterEcho]) && len(call.Args) >= 2:
+		router := RouterGin
+		if !e.routers[RouterGin] {
+			router = RouterEcho
+		}
+		if path, ok := stringLit(call.Args[0]); ok {
+			// gin 可以传多个处理函数，最后一个是真正的 handler；echo 的中间件在 handler 之后
+			handler := call.Args[len(call.Args)-1]
+			if router == RouterEcho {
+				handler = call.Args[1]
+			}
+			e.add(call, upperMethods[name], prefix+path, handler, router)
+		}
+	case chiMethods[name] != "" && e.routers[RouterChi] && len(call.Args) == 2:
+		if path, ok := stringLit(call.Args[0]); ok {
+			e.add(call, chiMethods[name], prefix+path, call.Args[1], RouterChi)
+		}
+	case (name == "Handle" || name == "Method" || name == "MethodFunc") && len(call.Args) == 3:
+		// gin: r.Handle("GET", "/p", h)   chi: r.Method("GET", "/p", h)
+		method, ok1 := stringLit(call.Args[0])
+		path, ok2 := stringLit(call.Args[1])
+		if ok1 && ok2 {
+			// 其他类型的同名方法不是路由注册
+			router := RouterChi
+			if e.routers[RouterGin] {
+				router = RouterGin
+			} else if !e.routers[RouterChi] {
+				return
+			}
+			e.add(call, strings.ToUpper(method), prefix+path, call.Args[2], router)
+		}
+	case (name == "HandleFunc" || name == "Handle") && len(call.Args) == 2:
+		path, ok := stringLit(call.Args[0])
+		if !ok {
+			return
+		}
+		// 接收者既不是 http 包或 *http.ServeMux，文件也没有导入其他路由框架时，不是路由注册
+		var router string
+		if e.isServeMux(sel.X) {
+			router = RouterNetHTTP
+		} else if e.routers[RouterMux] {
+			router = RouterMux
+		} else if e.routers[RouterChi] {
+			router = RouterChi
+		} else {
+			return
+		}
+		method := "ANY"
+		// Go 1.22 ServeMux 支持 "GET /path" 形式的模式
+		if fields := strings.Fields(path); len(fields) == 2 && router == RouterNetHTTP {
+			method, path = fields[0], fields[1]
+		}
+		e.add(call, method, prefix+path, call.Args[1], router)
+	case name == "Methods" && e.routers[RouterMux]:
+		// gorilla/mux: r.HandleFunc("/p", h).Methods("GET", "POST")
+		// 外层调用先于内层被遍历，这里先处理内层的 HandleFunc
+		inner, ok := sel.X.(*ast.CallExpr)
+		if !ok {
+			return
+		}
+		count := len(e.endpoints)
+		e.inspectCall(inner, prefixes)
+		if len(e.endpoints) == count+1 {
+			var methods []string
+			for _, arg := range call.Args {
+				if m, ok := stringLit(arg); ok {
+					methods = append(methods, strings.ToUpper(m))
+				}
+			}
+			if len(methods) > 0 {
+				e.endpoints[count].Method = strings.Join(methods, ",")
+			}
+		}
+	}
+}
+
+// isServeMux 判断表达式是否为 http 包本身或 *http.ServeMux 类型的变量
+func (e *routeExtractor) isServeMux(expr ast.Expr) bool {
+	if e.netHTTP == "" {
+		return false
+	}
+	if ident, ok := expr.(*ast.Ident); ok && ident.Name == e.netHTTP && e.exprType(ident) == "" {
+		return true
+	}
+	return receiverTypeName(e.exprType(expr)) == e.netHTTP+".ServeMux"
+}
+
+func (e *routeExtractor) add(call *ast.CallExpr, method, path string, handler ast.Expr, router string) {
+	handlerName, key := "<inline>", ""
+	if _, ok := handler.(*ast.FuncLit); !ok {
+		handlerName, key = shortExpr(handler), e.resolveHandler(handler)
+	}
+	e.endpoints = append(e.endpoints, &Endpoint{
+		Method:     method,
+		Path:       path,
+		Handler:    handlerName,
+		HandlerKey: key,
+		Router:     router,
+		File:       e.filePath,
+		Line:       e.fset.Position(call.Pos()).Line,
+	})
+}
+
+// extractHandlerIO 识别文件中每个函数绑定的请求结构体和返回的响应结构体，键为 HandlerKey
+func extractHandlerIO(f *ast.File) map[string]*HandlerIO {
+	handlers := make(map[string]*HandlerIO)
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		if io := handlerIO(fn.Body); io.RequestType != "" || io.ResponseType != "" {
+			handlers[FuncHandlerKey(f.Name.Name, funcDeclName(fn))] = io
+		}
+	}
+	return handlers
+}
+
+func handlerIO(body *ast.BlockStmt) *HandlerIO {
+	io := &HandlerIO{}
+	// 局部变量 -> 类型
+	varTypes := make(map[string]string)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.ValueSpec:
+			if t.Type != nil {
+				for _, name := range t.Names {
+					varTypes[name.Name] = exprToString(t.Type)
+				}
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range t.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if ok && i < len(t.Rhs) {
+					if typeName := literalType(t.Rhs[i]); typeName != "" {
+						varTypes[ident.Name] = typeName
+					}
+				}
+			}
+		case *ast.CallExpr:
+			sel, ok := t.Fun.(*ast.SelectorExpr)
+			if !ok || len(t.Args) == 0 {
+				return true
+			}
+			if bindMethods[sel.Sel.Name] && io.RequestType == "" {
+				io.RequestType = argType(t.Args[0], varTypes)
+			}
+			if respondMethods[sel.Sel.Name] && io.ResponseType == "" {
+				io.ResponseType = argType(t.Args[len(t.Args)-1], varTypes)
+			}
+		}
+		return true
+	})
+	return io
+}
+
+// argType 推断 &req、resp、T{...} 形式参数的类型
+func argType(arg ast.Expr, varTypes map[string]string) string {
+	if unary, ok := arg.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		arg = unary.X
+	}
+	if ident, ok := arg.(*ast.Ident); ok {
+		return strings.TrimPrefix(varTypes[ident.Name], "*")
+	}
+	return strings.TrimPrefix(literalType(arg), "*")
+}
+
+// literalType 返回 T{}、&T{}、new(T) 表达式的类型
+func literalType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.CompositeLit:
+		if t.Type != nil {
+			return exprToString(t.Type)
+		}
+	case *ast.UnaryExpr:
+		if t.Op == token.AND {
+			return literalType(t.X)
+		}
+	case *ast.CallExpr:
+		if ident, ok := t.Fun.(*ast.Ident); ok && ident.Name == "new" && len(t.Args) == 1 {
+			return exprToString(t.Args[0])
+		}
+	}
+	return ""
+}
+
+// FuncHandlerKey 由包名和 funcDeclName 形式的函数名生成 HandlerKey，例如 api + (*UserHandler).Create -> api.UserHandler.Create
+func FuncHandlerKey(pkg, name string) string {
+	if recv, method, ok := strings.Cut(name, ")."); ok {
+		return pkg + "." + receiverTypeName(strings.TrimPrefix(recv, "(")) + "." + method
+	}
+	return pkg + "." + name
+}
+
+// receiverTypeName 去掉类型的指针和类型参数，例如 *List[T] -> List
+func receiverTypeName(typeName string) string {
+	typeName = strings.TrimLeft(typeName, "*")
+	if i := strings.Index(typeName, "["); i >= 0 {
+		typeName = typeName[:i]
+	}
+	return typeName
+}
+
+// handlerName 处理函数表达式的最后一段名称，例如 h.CreateUser -> CreateUser
+func handlerName(handler string) string {
+	if i := strings.LastIndex(handler, "."); i >= 0 {
+		return handler[i+1:]
+	}
+	return handler
+}
+
+// ResolveEndpointTypes 使用整个项目的处理函数信息补全跨文件 handler 的请求/响应类型。
+// 无法确定接收者类型的处理函数只在项目中只有一个同名处理函数时匹配
+func ResolveEndpointTypes(results []*ParseResult) []*Endpoint {
+	handlers := make(map[string]*HandlerIO)
+	byName := make(map[string][]*HandlerIO)
+	for _, result := range results {
+		for key, io := range result.HandlerIO {
+			handlers[key] = io
+			byName[handlerName(key)] = append(byName[handlerName(key)], io)
+		}
+	}
+
+	var endpoints []*Endpoint
+	for _, result := range results {
+		for _, ep := range result.Endpoints {
+			io, ok := handlers[ep.HandlerKey]
+			if !ok && ep.HandlerKey == "" && ep.Handler != "<inline>" && len(byName[handlerName(ep.Handler)]) == 1 {
+				io, ok = byName[handlerName(ep.Handler)][0], true
+			}
+			if ok {
+				if ep.RequestType == "" {
+					ep.RequestType = io.RequestType
+				}
+				if ep.ResponseType == "" {
+					ep.ResponseType = io.ResponseType
+				}
+			}
+			endpoints = append(endpoints, ep)
+		}
+	}
+	SortEndpoints(endpoints)
+	return endpoints
+}
+
+// SortEndpoints 按路径、方法排序
+func SortEndpoints(endpoints []*Endpoint) {
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if endpoints[i].Path != endpoints[j].Path {
+			return endpoints[i].Path < endpoints[j].Path
+		}
+		return endpoints[i].Method < endpoints[j].Method
+	})
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", false
+	}
+	return s, true
+}
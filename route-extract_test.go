@@ -0,0 +1,181 @@
+package code
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestExtractEndpoints(t *testing.T) {
+	src := `package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/mux"
+)
+
+type CreateUserReq struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type UserResp struct {
+	ID int ` + "`json:\"id\"`" + `
+}
+
+func CreateUser(c *gin.Context) {
+	var req CreateUserReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return
+	}
+	c.JSON(http.StatusOK, UserResp{ID: 1})
+}
+
+func register(r *gin.Engine, m *mux.Router) {
+	v1 := r.Group("/api/v1")
+	v1.POST("/users", auth, CreateUser)
+	r.GET("/ping", func(c *gin.Context) {})
+
+	s := m.PathPrefix("/admin").Subrouter()
+	s.HandleFunc("/stats", stats).Methods("GET", "post")
+	http.HandleFunc("DELETE /items/{id}", deleteItem)
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "api.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints := extractEndpoints(fset, "api.go", f)
+
+	want := []Endpoint{
+		{Method: "POST", Path: "/api/v1/users", Handler: "CreateUser", HandlerKey: "api.CreateUser", Router: RouterGin, RequestType: "CreateUserReq", ResponseType: "UserResp"},
+		{Method: "GET", Path: "/ping", Handler: "<inline>", Router: RouterGin},
+		{Method: "GET,POST", Path: "/admin/stats", Handler: "stats", HandlerKey: "api.stats", Router: RouterMux},
+		{Method: "DELETE", Path: "/items/{id}", Handler: "deleteItem", HandlerKey: "api.deleteItem", Router: RouterNetHTTP},
+	}
+	if len(endpoints) != len(want) {
+		t.Fatalf("got %d endpoints, want %d: %v", len(endpoints), len(want), endpoints)
+	}
+	for i, w := range want {
+		got := *endpoints[i]
+		got.File, got.Line = "", 0
+		if got != w {
+			t.Errorf("endpoint %d = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestResolveHandlersByReceiver(t *testing.T) {
+	src := `package api
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandler struct{}
+
+type OrderHandler struct{}
+
+type Server struct {
+	orders *OrderHandler
+}
+
+func NewUserHandler() *UserHandler { return &UserHandler{} }
+
+func (h *UserHandler) Create(c *gin.Context) {
+	var req CreateUserReq
+	c.ShouldBindJSON(&req)
+}
+
+func (h *OrderHandler) Create(c *gin.Context) {
+	var req CreateOrderReq
+	c.ShouldBindJSON(&req)
+}
+
+func users(r *gin.Engine) {
+	g := r.Group("/users")
+	h := NewUserHandler()
+	g.POST("", h.Create)
+}
+
+func (s *Server) orderRoutes(r *gin.Engine) {
+	g := r.Group("/orders")
+	g.POST("", s.orders.Create)
+}
+
+func health(r *gin.Engine, g *gin.RouterGroup) {
+	g.GET("/health", ping)
+}
+
+func chiRoutes(r chi.Router) {
+	r.Route("/items", func(r chi.Router) {
+		r.Get("/", listItems)
+	})
+	r.Get("/status", status)
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "api.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints := extractEndpoints(fset, "api.go", f)
+	want := map[string]Endpoint{
+		"POST /users":  {HandlerKey: "api.UserHandler.Create", RequestType: "CreateUserReq"},
+		"POST /orders": {HandlerKey: "api.OrderHandler.Create", RequestType: "CreateOrderReq"},
+		"GET /health":  {HandlerKey: "api.ping"},
+		"GET /items/":  {HandlerKey: "api.listItems"},
+		"GET /status":  {HandlerKey: "api.status"},
+	}
+	if len(endpoints) != len(want) {
+		t.Fatalf("got %d endpoints, want %d: %v", len(endpoints), len(want), endpoints)
+	}
+	for _, ep := range endpoints {
+		w, ok := want[ep.Method+" "+ep.Path]
+		if !ok {
+			t.Errorf("unexpected endpoint %s", ep.String())
+			continue
+		}
+		if ep.HandlerKey != w.HandlerKey || ep.RequestType != w.RequestType {
+			t.Errorf("%s: key = %s, request = %s, want %s, %s", ep.String(), ep.HandlerKey, ep.RequestType, w.HandlerKey, w.RequestType)
+		}
+	}
+}
+
+func TestExtractEndpointsIgnoresNonRouters(t *testing.T) {
+	src := `package api
+
+import "net/http"
+
+var mux = http.NewServeMux()
+
+type Client struct{}
+
+func (c *Client) HandleFunc(name string, f func()) {}
+func (c *Client) Handle(kind, name string, f func()) {}
+
+func register(client *Client, own *http.ServeMux) {
+	client.HandleFunc("x", nil)
+	client.Handle("GET", "/y", nil)
+	mux.HandleFunc("/global", global)
+	own.Handle("/own", nil)
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "api.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, ep := range extractEndpoints(fset, "api.go", f) {
+		got = append(got, ep.Method+" "+ep.Path+" "+ep.Router)
+	}
+	want := []string{"ANY /global net/http", "ANY /own net/http"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("endpoints = %v, want %v", got, want)
+	}
+}
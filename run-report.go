@@ -0,0 +1,72 @@
+package code
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 运行报告中记录的文件处理方式
+const (
+	ReportActionAnalyzed = "analyzed"
+	ReportActionChunked  = "chunked"
+	ReportActionStatic   = "static"
+	ReportActionSkipped  = "skipped"
+	ReportActionFailed   = "failed"
+)
+
+// FileDecision 单个文件的处理方式及原因
+type FileDecision struct {
+	Path   string `yaml:"path"`
+	Action string `yaml:"action"`
+	Reason string `yaml:"reason,omitempty"`
+}
+
+// RunReport 一次分析的运行报告
+type RunReport struct {
+	Root       string          `yaml:"root"`
+	StartedAt  time.Time       `yaml:"started_at"`
+	FinishedAt time.Time       `yaml:"finished_at"`
+	Files      []*FileDecision `yaml:"files"`
+
+	mu sync.Mutex
+}
+
+// NewRunReport 创建运行报告
+func NewRunReport(root string) *RunReport {
+	return &RunReport{Root: root, StartedAt: time.Now()}
+}
+
+// Record 记录文件的处理方式
+func (r *RunReport) Record(path, action, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Files = append(r.Files, &FileDecision{Path: path, Action: action, Reason: reason})
+}
+
+// Count 统计某种处理方式的文件数
+func (r *RunReport) Count(action string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, decision := range r.Files {
+		if decision.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Save 结束运行并把报告写入文件
+func (r *RunReport) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.FinishedAt = time.Now()
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,283 @@
+package code
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SearchIndexFileName 输出目录中的本地检索索引
+const SearchIndexFileName = "search-index.yaml"
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// SearchDoc 检索索引中的一个文件
+type SearchDoc struct {
+	Path    string `yaml:"path"`
+	Package string `yaml:"package"`
+	// Symbols 文件中声明的类型、函数和方法，方法使用 Type.Method 的形式
+	Symbols []string `yaml:"symbols,omitempty"`
+	Length  int      `yaml:"length"`
+	// Terms 词 -> 出现次数
+	Terms map[string]int `yaml:"terms"`
+}
+
+// SearchIndex 基于 BM25 的本地检索索引，索引文件的摘要、路径、标识符和文档注释，不依赖网络
+type SearchIndex struct {
+	Docs []*SearchDoc `yaml:"docs"`
+}
+
+// SearchHit 检索结果
+type SearchHit struct {
+	Path    string
+	Package string
+	Score   float64
+	// Matched 命中的查询词
+	Matched []string
+}
+
+// BuildSearchIndex 根据文件摘要和静态解析结果建立检索索引，symbols 的键为条目的相对路径
+func BuildSearchIndex(entries []*SummaryEntry, symbols map[string]*ParseResult) *SearchIndex {
+	index := &SearchIndex{}
+	for _, entry := range entries {
+		doc := &SearchDoc{Path: entry.Path, Package: entry.Package(), Terms: make(map[string]int)}
+		texts := []string{
+			entry.Path,
+			entry.Package(),
+			entry.Result.FunctionDescription,
+			entry.Result.FileInfo.PackageName,
+			strings.Join(entry.Result.FileInfo.Imports, " "),
+		}
+		for _, ep := range entry.Result.StaticEndpoints {
+			texts = append(texts, ep.String())
+		}
+		for _, behavior := range entry.Result.TestedBehaviors {
+			texts = append(texts, behavior.Test, behavior.Behavior)
+		}
+		if result := symbols[entry.Path]; result != nil {
+			doc.Symbols = ParseResultSymbols(result)
+			texts = append(texts, doc.Symbols...)
+			texts = append(texts, result.Constants...)
+			texts = append(texts, result.ExportedVar...)
+			texts = append(texts, result.UnexportedVar...)
+			texts = append(texts, result.Declarations...)
+			texts = append(texts, result.Docs...)
+			for _, info := range result.Structs {
+				texts = append(texts, info.Fields...)
+			}
+		}
+		for _, text := range texts {
+			for _, term := range SearchTokens(text) {
+				doc.Terms[term]++
+				doc.Length++
+			}
+		}
+		index.Docs = append(index.Docs, doc)
+	}
+	return index
+}
+
+// ParseResultSymbols 文件中声明的类型、接口和函数名，方法使用 Type.Method 的形式，按名称排序
+func ParseResultSymbols(result *ParseResult) []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for name := range result.Structs {
+		add(name)
+	}
+	for name := range result.Interfaces {
+		add(name)
+	}
+	for _, fn := range result.Funcs {
+		if fn.Receiver != "" {
+			add(strings.TrimPrefix(fn.Receiver, "*") + "." + fn.Name)
+		} else {
+			add(fn.Name)
+		}
+	}
+	// 非 Go 语言没有函数指标，使用签名中的函数名
+	if len(result.Funcs) == 0 {
+		for _, signature := range append(append([]string{}, result.ExportedFunc...), result.UnexportedFunc...) {
+			name, _, _ := strings.Cut(signature, "(")
+			add(name)
+		}
+		for typeName, info := range result.Structs {
+			for _, signature := range info.Methods {
+				name, _, _ := strings.Cut(signature, "(")
+				add(typeName + "." + name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadSearchIndex 读取检索索引，文件不存在时返回 nil
+func LoadSearchIndex(path string) (*SearchIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	index := &SearchIndex{}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// Save 写入检索索引
+func (idx *SearchIndex) Save(path string) error {
+	data, err := yaml.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Search 使用 BM25 为文件打分，返回得分最高的 limit 个文件，limit <= 0 时返回所有命中的文件
+func (idx *SearchIndex) Search(query string, limit int) []*SearchHit {
+	if len(idx.Docs) == 0 {
+		return nil
+	}
+	terms := uniqueStrings(SearchTokens(query))
+	total := 0
+	for _, doc := range idx.Docs {
+		total += doc.Length
+	}
+	avgLength := float64(total) / float64(len(idx.Docs))
+	if avgLength == 0 {
+		return nil
+	}
+
+	idf := make(map[string]float64, len(terms))
+	n := float64(len(idx.Docs))
+	for _, term := range terms {
+		df := 0
+		for _, doc := range idx.Docs {
+			if doc.Terms[term] > 0 {
+				df++
+			}
+		}
+		idf[term] = math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+	}
+
+	var hits []*SearchHit
+	for _, doc := range idx.Docs {
+		hit := &SearchHit{Path: doc.Path, Package: doc.Package}
+		for _, term := range terms {
+			tf := float64(doc.Terms[term])
+			if tf == 0 {
+				continue
+			}
+			hit.Score += idf[term] * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(doc.Length)/avgLength))
+			hit.Matched = append(hit.Matched, term)
+		}
+		if hit.Score > 0 {
+			hits = append(hits, hit)
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Path < hits[j].Path
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// SearchTokens 把文本切分为检索使用的词：标识符按驼峰和下划线拆分并转为小写(同时保留完整的标识符)，
+// 中文按相邻两个字切分，单个字母和数字忽略
+func SearchTokens(text string) []string {
+	var tokens []string
+	var word, han []rune
+	flushWord := func() {
+		if len(word) == 0 {
+			return
+		}
+		parts := splitIdentifier(string(word))
+		for _, part := range parts {
+			if len([]rune(part)) > 1 {
+				tokens = append(tokens, part)
+			}
+		}
+		if len(parts) > 1 {
+			tokens = append(tokens, strings.ToLower(string(word)))
+		}
+		word = word[:0]
+	}
+	flushHan := func() {
+		if len(han) == 1 {
+			tokens = append(tokens, string(han))
+		}
+		for i := 0; i+1 < len(han); i++ {
+			tokens = append(tokens, string(han[i:i+2]))
+		}
+		han = han[:0]
+	}
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return tokens
+}
+
+// splitIdentifier 按驼峰拆分标识符并转为小写，例如 ParseHTTPRequest -> parse, http, request
+func splitIdentifier(word string) []string {
+	runes := []rune(word)
+	var parts []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		boundary := unicode.IsLower(prev) && unicode.IsUpper(cur) ||
+			unicode.IsDigit(prev) != unicode.IsDigit(cur) ||
+			// HTTPRequest 中 P 与 R 之间
+			i+1 < len(runes) && unicode.IsUpper(prev) && unicode.IsUpper(cur) && unicode.IsLower(runes[i+1])
+		if boundary {
+			parts = append(parts, strings.ToLower(string(runes[start:i])))
+			start = i
+		}
+	}
+	return append(parts, strings.ToLower(string(runes[start:])))
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,54 @@
+package code
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSearchTokens(t *testing.T) {
+	got := SearchTokens("ParseHTTPRequest 用户登录, retry_count v2")
+	want := []string{"parse", "http", "request", "parsehttprequest", "用户", "户登", "登录", "retry", "count", "v2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchTokens = %v, want %v", got, want)
+	}
+}
+
+func TestSearchIndex(t *testing.T) {
+	entries := []*SummaryEntry{
+		{Path: "auth/login.go", Result: ParsedYAML{FunctionDescription: "处理用户登录和会话", ImportPath: "example.com/m/auth"}},
+		{Path: "client/retry.go", Result: ParsedYAML{FunctionDescription: "HTTP 客户端", ImportPath: "example.com/m/client"}},
+		{Path: "store/user.go", Result: ParsedYAML{FunctionDescription: "用户数据的存储", ImportPath: "example.com/m/store"}},
+	}
+	symbols := map[string]*ParseResult{
+		"client/retry.go": {
+			Funcs: []*FuncMetrics{{Name: "Do", Receiver: "*RetryClient"}, {Name: "backoff"}},
+			Docs:  []string{"RetryClient 失败时按指数退避重试请求"},
+		},
+	}
+	index := BuildSearchIndex(entries, symbols)
+	if want := []string{"RetryClient.Do", "backoff"}; !reflect.DeepEqual(index.Docs[1].Symbols, want) {
+		t.Errorf("symbols = %v, want %v", index.Docs[1].Symbols, want)
+	}
+
+	path := filepath.Join(t.TempDir(), SearchIndexFileName)
+	if err := index.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSearchIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hits := loaded.Search("请求失败后如何重试", 0)
+	if len(hits) == 0 || hits[0].Path != "client/retry.go" {
+		t.Fatalf("hits = %v, want client/retry.go first", hits)
+	}
+	hits = loaded.Search("用户登录", 1)
+	if len(hits) != 1 || hits[0].Path != "auth/login.go" {
+		t.Errorf("hits = %v, want only auth/login.go", hits)
+	}
+	if hits := loaded.Search("kubernetes", 0); len(hits) != 0 {
+		t.Errorf("unexpected hits: %v", hits)
+	}
+}
@@ -0,0 +1,99 @@
+package code
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BuildStaticSummary 不调用 AI，根据静态解析结果生成文件摘要，返回 YAML 文本和解析后的结构
+func BuildStaticSummary(path, description string, result *ParseResult) (string, ParsedYAML, error) {
+	parsed := ParsedYAML{
+		FunctionDescription: description,
+		FileInfo: FileInfo{
+			FileName: filepath.Base(path),
+		},
+	}
+	if result != nil {
+		parsed.FileInfo.PackageName = result.PackageName
+		parsed.FileInfo.Imports = result.Imports
+		parsed.StaticEndpoints = result.Endpoints
+		if result.Language != LanguageGo {
+			parsed.Language = result.Language
+		}
+	}
+	data, err := yaml.Marshal(parsed)
+	if err != nil {
+		return "", parsed, err
+	}
+	return string(data), parsed, nil
+}
+
+// ProtoGeneratedDescription 生成 protobuf 生成代码的描述，列出其中的消息结构体和服务接口
+func ProtoGeneratedDescription(result *ParseResult) string {
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString("protobuf/gRPC 生成的代码(静态摘要，未经 AI 分析)。")
+	if names := sortedKeys(result.Structs); len(names) > 0 {
+		strBuilder.WriteString("消息结构体: ")
+		strBuilder.WriteString(strings.Join(names, ", "))
+		strBuilder.WriteString("。")
+	}
+	if names := sortedKeys(result.Interfaces); len(names) > 0 {
+		strBuilder.WriteString("接口: ")
+		strBuilder.WriteString(strings.Join(names, ", "))
+		strBuilder.WriteString("。")
+	}
+	return strBuilder.String()
+}
+
+// BuildProtoSummary 根据 .proto 文件的解析结果生成文件摘要
+func BuildProtoSummary(proto *ProtoFile) (string, ParsedYAML, error) {
+	strBuilder := strings.Builder{}
+	strBuilder.WriteString(fmt.Sprintf("protobuf 定义文件，包 %s。", proto.Package))
+	for _, service := range proto.Services {
+		var rpcs []string
+		for _, rpc := range service.RPCs {
+			rpcs = append(rpcs, rpc.String())
+		}
+		strBuilder.WriteString(fmt.Sprintf("服务 %s: %s。", service.Name, strings.Join(rpcs, "; ")))
+	}
+	if len(proto.Messages) > 0 {
+		var names []string
+		for _, message := range proto.Messages {
+			names = append(names, message.Name)
+		}
+		strBuilder.WriteString("消息: ")
+		strBuilder.WriteString(strings.Join(names, ", "))
+		strBuilder.WriteString("。")
+	}
+
+	parsed := ParsedYAML{
+		FunctionDescription: strBuilder.String(),
+		FileInfo: FileInfo{
+			FileName:    filepath.Base(proto.File),
+			PackageName: proto.Package,
+			Imports:     proto.Imports,
+		},
+	}
+	data, err := yaml.Marshal(struct {
+		ParsedYAML `yaml:",inline"`
+		Proto      *ProtoFile `yaml:"proto"`
+	}{parsed, proto})
+	if err != nil {
+		return "", parsed, err
+	}
+	return string(data), parsed, nil
+}
+
+// sortedKeys 返回排序后的 map 键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,236 @@
+package code
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SummaryTreeFileName 输出目录中保存包级摘要和架构概览的文件
+const SummaryTreeFileName = "summary-tree.yaml"
+
+// ArchitectureFileName 输出目录中便于阅读的架构概览
+const ArchitectureFileName = "architecture.md"
+
+// PackageSummary 根据包内各文件的摘要生成的包级摘要
+type PackageSummary struct {
+	// Package 包的导入路径，无法确定导入路径时为相对于分析目录的目录
+	Package string   `yaml:"package"`
+	Module  string   `yaml:"module,omitempty"`
+	Files   []string `yaml:"files"`
+	// Description AI 生成的包摘要
+	Description string `yaml:"description"`
+	// Hash 包内文件摘要的哈希，未变化时复用已有的摘要
+	Hash string `yaml:"hash"`
+	// Error 生成摘要失败的原因，下一次运行时重新生成
+	Error string `yaml:"error,omitempty"`
+}
+
+// SummaryTree 三级摘要中的上两级：仓库架构概览和包摘要，文件摘要保存在各文件的分析结果中
+type SummaryTree struct {
+	Overview string `yaml:"overview"`
+	// OverviewHash 所有包摘要的哈希，未变化时复用已有的概览
+	OverviewHash string            `yaml:"overview_hash"`
+	Packages     []*PackageSummary `yaml:"packages"`
+}
+
+// LoadSummaryTree 读取摘要树，文件不存在时返回 nil
+func LoadSummaryTree(path string) (*SummaryTree, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	tree := &SummaryTree{}
+	if err := yaml.Unmarshal(data, tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// Save 写入摘要树
+func (t *SummaryTree) Save(path string) error {
+	data, err := yaml.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Package 按名称查找包摘要
+func (t *SummaryTree) Package(name string) *PackageSummary {
+	for _, pkg := range t.Packages {
+		if pkg.Package == name {
+			return pkg
+		}
+	}
+	return nil
+}
+
+// FormatPackages 按模块列出包及其摘要，用于生成架构概览和问答时选择相关的包
+func (t *SummaryTree) FormatPackages() string {
+	var strBuilder strings.Builder
+	for _, section := range t.PackageSections() {
+		strBuilder.WriteString(section.Text)
+	}
+	return strBuilder.String()
+}
+
+// PackageSections 按模块列出的包摘要，每个包一段，预算不足时只保留包名和文件数量
+func (t *SummaryTree) PackageSections() []BudgetSection {
+	var sections []BudgetSection
+	module := ""
+	for i, pkg := range t.Packages {
+		header := ""
+		if i == 0 || pkg.Module != module {
+			module = pkg.Module
+			if module != "" {
+				header = fmt.Sprintf("模块: %s\n", module)
+			}
+		}
+		name := fmt.Sprintf("- 包: %s (%d 个文件)\n", pkg.Package, len(pkg.Files))
+		sections = append(sections, BudgetSection{
+			Name:    pkg.Package + " 的包摘要",
+			Text:    header + name + fmt.Sprintf("  摘要: %s\n", strings.TrimSpace(pkg.Description)),
+			Summary: header + name,
+		})
+	}
+	return sections
+}
+
+// Markdown 便于阅读的架构概览和包摘要
+func (t *SummaryTree) Markdown() string {
+	var strBuilder strings.Builder
+	strBuilder.WriteString("# 架构概览\n\n")
+	strBuilder.WriteString(strings.TrimSpace(t.Overview))
+	strBuilder.WriteString("\n\n## 包\n\n")
+	for _, pkg := range t.Packages {
+		strBuilder.WriteString(fmt.Sprintf("### %s\n\n%s\n\n文件: %s\n\n", pkg.Package, strings.TrimSpace(pkg.Description), strings.Join(pkg.Files, ", ")))
+	}
+	return strBuilder.String()
+}
+
+// BuildSummaryTree 根据文件摘要逐级生成包摘要和架构概览
+//
+// previous 为上一次运行的摘要树，包内文件摘要未变化的包以及包摘要均未变化时的概览直接复用，不再调用 AI。
+// 单个包或概览生成失败时记录在摘要树中并继续，返回的摘要树总是可以保存，error 汇总所有失败
+func BuildSummaryTree(entries []*SummaryEntry, previous *SummaryTree,
+	summarizePackage func(pkg, fileSummaries string) (string, error),
+	summarizeArchitecture func(packages string) (string, error)) (*SummaryTree, error) {
+	SortSummaryEntries(entries)
+
+	tree := &SummaryTree{}
+	packages := make(map[string]*PackageSummary)
+	texts := make(map[string]*strings.Builder)
+	for _, entry := range entries {
+		name := entry.Package()
+		pkg, ok := packages[name]
+		if !ok {
+			pkg = &PackageSummary{Package: name, Module: entry.Result.ModulePath}
+			packages[name] = pkg
+			texts[name] = &strings.Builder{}
+			tree.Packages = append(tree.Packages, pkg)
+		}
+		pkg.Files = append(pkg.Files, entry.Path)
+		texts[name].WriteString(FormatSummaryEntry(entry.Path, &entry.Result))
+	}
+	// 同一模块的包相邻，便于按模块输出
+	sort.SliceStable(tree.Packages, func(i, j int) bool {
+		return tree.Packages[i].Module < tree.Packages[j].Module
+	})
+
+	var errs []error
+	for _, pkg := range tree.Packages {
+		text := texts[pkg.Package].String()
+		pkg.Hash = summaryHash(text)
+		if previous != nil {
+			if old := previous.Package(pkg.Package); old != nil && old.Hash == pkg.Hash && old.Description != "" {
+				pkg.Description = old.Description
+				continue
+			}
+		}
+		description, err := summarizePackage(pkg.Package, text)
+		if err != nil {
+			// 清空哈希，下一次运行时重新生成；之前的摘要虽然过期，仍然比没有好
+			pkg.Hash, pkg.Error = "", err.Error()
+			if previous != nil {
+				if old := previous.Package(pkg.Package); old != nil {
+					pkg.Description = old.Description
+				}
+			}
+			errs = append(errs, fmt.Errorf("failed to summarize package %s: %v", pkg.Package, err))
+			continue
+		}
+		pkg.Description = strings.TrimSpace(description)
+	}
+
+	packageSummaries := tree.FormatPackages()
+	tree.OverviewHash = summaryHash(packageSummaries)
+	if previous != nil && previous.OverviewHash == tree.OverviewHash && previous.Overview != "" {
+		tree.Overview = previous.Overview
+		return tree, errors.Join(errs...)
+	}
+	// 有包摘要失败时概览下一次还要重新生成，先沿用之前的概览
+	if len(errs) > 0 {
+		tree.OverviewHash = ""
+		if previous != nil {
+			tree.Overview = previous.Overview
+		}
+		return tree, errors.Join(errs...)
+	}
+	overview, err := summarizeArchitecture(packageSummaries)
+	if err != nil {
+		tree.OverviewHash = ""
+		if previous != nil {
+			tree.Overview = previous.Overview
+		}
+		return tree, fmt.Errorf("failed to summarize architecture: %v", err)
+	}
+	tree.Overview = strings.TrimSpace(overview)
+	return tree, nil
+}
+
+// LoadSummaryEntries 根据结果索引读取所有文件的分析结果，无法读取的结果会被跳过并通过 onError 报告
+func LoadSummaryEntries(outputDir string, index *ResultIndex, onError func(source string, err error)) []*SummaryEntry {
+	var entries []*SummaryEntry
+	for _, source := range index.Sources() {
+		result, err := LoadResult(filepath.Join(outputDir, filepath.FromSlash(index.Files[source])))
+		if err != nil {
+			if onError != nil {
+				onError(source, err)
+			}
+			continue
+		}
+		entries = append(entries, &SummaryEntry{Path: source, Result: result})
+	}
+	return entries
+}
+
+// FilterEntriesByPackage 只保留属于指定包的条目
+func FilterEntriesByPackage(entries []*SummaryEntry, packages []string) []*SummaryEntry {
+	selected := make(map[string]bool, len(packages))
+	for _, pkg := range packages {
+		selected[pkg] = true
+	}
+	var filtered []*SummaryEntry
+	for _, entry := range entries {
+		if selected[entry.Package()] {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+func summaryHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,108 @@
+package code
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildSummaryTree(t *testing.T) {
+	entries := []*SummaryEntry{
+		{Path: "svc/b.go", Result: ParsedYAML{FunctionDescription: "B", ModulePath: "example.com/m", ImportPath: "example.com/m/svc"}},
+		{Path: "svc/a.go", Result: ParsedYAML{FunctionDescription: "A", ModulePath: "example.com/m", ImportPath: "example.com/m/svc"}},
+		{Path: "tools/gen.py", Result: ParsedYAML{FunctionDescription: "gen", Language: LanguagePython}},
+	}
+	var summarized []string
+	summarizePackage := func(pkg, fileSummaries string) (string, error) {
+		summarized = append(summarized, pkg)
+		return "summary of " + pkg + "\n", nil
+	}
+	overviews := 0
+	summarizeArchitecture := func(packages string) (string, error) {
+		overviews++
+		if !strings.Contains(packages, "模块: example.com/m") {
+			t.Errorf("packages not grouped by module:\n%s", packages)
+		}
+		return "overview", nil
+	}
+
+	tree, err := BuildSummaryTree(entries, nil, summarizePackage, summarizeArchitecture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"tools", "example.com/m/svc"}; !reflect.DeepEqual(summarized, want) {
+		t.Errorf("summarized = %v, want %v", summarized, want)
+	}
+	svc := tree.Package("example.com/m/svc")
+	if svc == nil || !reflect.DeepEqual(svc.Files, []string{"svc/a.go", "svc/b.go"}) || svc.Description != "summary of example.com/m/svc" {
+		t.Fatalf("unexpected svc package: %+v", svc)
+	}
+
+	// 只有变化的包会重新生成，包摘要没有变化时复用概览
+	summarized = nil
+	entries[2].Result.FunctionDescription = "generator"
+	if _, err := BuildSummaryTree(entries, tree, summarizePackage, summarizeArchitecture); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"tools"}; !reflect.DeepEqual(summarized, want) {
+		t.Errorf("summarized = %v, want %v", summarized, want)
+	}
+	if overviews != 1 {
+		t.Errorf("overview generated %d times, want 1", overviews)
+	}
+
+	filtered := FilterEntriesByPackage(entries, []string{"tools"})
+	if len(filtered) != 1 || filtered[0].Path != "tools/gen.py" {
+		t.Errorf("filtered = %v", filtered)
+	}
+}
+
+func TestBuildSummaryTreePartialFailure(t *testing.T) {
+	entries := []*SummaryEntry{
+		{Path: "a/a.go", Result: ParsedYAML{FunctionDescription: "A"}},
+		{Path: "b/b.go", Result: ParsedYAML{FunctionDescription: "B"}},
+	}
+	fail := true
+	summarizePackage := func(pkg, fileSummaries string) (string, error) {
+		if pkg == "b" && fail {
+			return "", errors.New("rate limited")
+		}
+		return "summary of " + pkg, nil
+	}
+	overviews := 0
+	summarizeArchitecture := func(packages string) (string, error) {
+		overviews++
+		return "overview", nil
+	}
+
+	// 失败的包记录原因，成功的包摘要保留，概览等到所有包成功后再生成
+	tree, err := BuildSummaryTree(entries, nil, summarizePackage, summarizeArchitecture)
+	if err == nil || tree == nil {
+		t.Fatalf("tree = %v, err = %v", tree, err)
+	}
+	if a := tree.Package("a"); a == nil || a.Description != "summary of a" || a.Hash == "" {
+		t.Errorf("unexpected a package: %+v", a)
+	}
+	if b := tree.Package("b"); b == nil || b.Error == "" || b.Hash != "" {
+		t.Errorf("unexpected b package: %+v", b)
+	}
+	if overviews != 0 || tree.OverviewHash != "" {
+		t.Errorf("overview generated with failed packages")
+	}
+
+	// 下一次运行只重新生成失败的包
+	fail = false
+	var summarized []string
+	retry := func(pkg, fileSummaries string) (string, error) {
+		summarized = append(summarized, pkg)
+		return summarizePackage(pkg, fileSummaries)
+	}
+	tree, err = BuildSummaryTree(entries, tree, retry, summarizeArchitecture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(summarized, []string{"b"}) || tree.Package("b").Error != "" || tree.Overview != "overview" {
+		t.Errorf("summarized = %v, tree = %+v", summarized, tree)
+	}
+}
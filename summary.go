@@ -0,0 +1,167 @@
+package code
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SummaryMeta 总结文件头部的运行信息
+type SummaryMeta struct {
+	Root          string
+	Commit        string
+	Model         string
+	PromptVersion string
+	Date          time.Time
+}
+
+// SummaryEntry 总结文件中的一个文件
+type SummaryEntry struct {
+	// Path 相对于分析目录的路径
+	Path   string
+	Result ParsedYAML
+	// Symbols 问答时附带的文件中的函数和类型，供 AI 选择具体的符号
+	Symbols []string
+}
+
+// maxEntrySymbols 问答时每个文件最多列出的符号数量
+const maxEntrySymbols = 30
+
+// Format 总结条目，带有文件中的符号
+func (e *SummaryEntry) Format() string {
+	return formatSummaryEntry(e.Path, &e.Result, e.Symbols)
+}
+
+// Package 条目所属的包：优先使用导入路径，否则使用所在目录
+func (e *SummaryEntry) Package() string {
+	if e.Result.ImportPath != "" {
+		return e.Result.ImportPath
+	}
+	return path.Dir(e.Path)
+}
+
+// LoadResult 读取单个文件的分析结果，分段分析的多文档结果会被合并
+func LoadResult(path string) (ParsedYAML, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ParsedYAML{}, err
+	}
+	var raws []string
+	var docs []ParsedYAML
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc ParsedYAML
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return ParsedYAML{}, err
+		}
+		raws = append(raws, "")
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return ParsedYAML{}, nil
+	}
+	_, merged := MergeChunkResults(raws, docs)
+	return merged, nil
+}
+
+// SortSummaryEntries 按包和路径排序
+func SortSummaryEntries(entries []*SummaryEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		pi, pj := entries[i].Package(), entries[j].Package()
+		if pi != pj {
+			return pi < pj
+		}
+		return entries[i].Path < entries[j].Path
+	})
+}
+
+// RenderSummary 根据分析结果生成总结文件，条目按包和路径排序，untested 为空时不输出未测试函数一节
+func RenderSummary(meta SummaryMeta, entries []*SummaryEntry, untested []*UntestedFunc) string {
+	SortSummaryEntries(entries)
+
+	var strBuilder strings.Builder
+	strBuilder.WriteString("# 代码分析总结\n")
+	strBuilder.WriteString(fmt.Sprintf("根目录: %s\n", meta.Root))
+	if meta.Commit != "" {
+		strBuilder.WriteString(fmt.Sprintf("提交: %s\n", meta.Commit))
+	}
+	strBuilder.WriteString(fmt.Sprintf("模型: %s\n", meta.Model))
+	strBuilder.WriteString(fmt.Sprintf("提示词版本: %s\n", meta.PromptVersion))
+	strBuilder.WriteString(fmt.Sprintf("生成时间: %s\n", meta.Date.Format(time.RFC3339)))
+	strBuilder.WriteString(fmt.Sprintf("文件数: %d\n", len(entries)))
+	strBuilder.WriteString("---\n")
+
+	for _, entry := range entries {
+		strBuilder.WriteString(FormatSummaryEntry(entry.Path, &entry.Result))
+	}
+	if len(untested) > 0 {
+		strBuilder.WriteString(FormatUntested(untested))
+	}
+	return strBuilder.String()
+}
+
+// FormatSummaryEntry 总结文件中单个文件的条目
+func FormatSummaryEntry(path string, result *ParsedYAML) string {
+	return formatSummaryEntry(path, result, nil)
+}
+
+func formatSummaryEntry(path string, result *ParsedYAML, symbols []string) string {
+	var strBuilder strings.Builder
+
+	strBuilder.WriteString(fmt.Sprintf("文件名: %s\n", path))
+	strBuilder.WriteString(fmt.Sprintf("功能: %s\n", strings.TrimSpace(result.FunctionDescription)))
+	strBuilder.WriteString(fmt.Sprintf("包名: %s\n", result.FileInfo.PackageName))
+	if result.Language != "" {
+		strBuilder.WriteString(fmt.Sprintf("语言: %s\n", result.Language))
+	}
+	if result.ModulePath != "" {
+		strBuilder.WriteString(fmt.Sprintf("模块: %s\n", result.ModulePath))
+		strBuilder.WriteString(fmt.Sprintf("导入路径: %s\n", result.ImportPath))
+	}
+	strBuilder.WriteString("依赖导入项目: ")
+	strBuilder.WriteString(strings.Join(result.FileInfo.Imports, ","))
+	strBuilder.WriteString("\n")
+	for _, ep := range result.StaticEndpoints {
+		strBuilder.WriteString(fmt.Sprintf("接口: %s\n", ep.String()))
+	}
+	for _, behavior := range result.TestedBehaviors {
+		strBuilder.WriteString(fmt.Sprintf("测试: %s %s", behavior.Test, behavior.Behavior))
+		if targets := result.TestTargets[behavior.Test]; len(targets) > 0 {
+			strBuilder.WriteString(fmt.Sprintf(" (被测函数: %s)", strings.Join(targets, ",")))
+		}
+		strBuilder.WriteString("\n")
+	}
+	if len(symbols) > 0 {
+		if len(symbols) > maxEntrySymbols {
+			symbols = symbols[:maxEntrySymbols]
+		}
+		strBuilder.WriteString(fmt.Sprintf("符号: %s\n", strings.Join(symbols, ",")))
+	}
+	strBuilder.WriteString("---\n")
+	return strBuilder.String()
+}
+
+// untestedSummaryTitle 总结文件中未被测试的导出函数一节的标题
+const untestedSummaryTitle = "未被测试的导出函数:"
+
+// FormatUntested 未被测试的导出函数一节
+func FormatUntested(untested []*UntestedFunc) string {
+	var strBuilder strings.Builder
+	strBuilder.WriteString(untestedSummaryTitle + "\n")
+	for _, fn := range untested {
+		strBuilder.WriteString(fmt.Sprintf("- %s (%s:%d)\n", fn.Name, fn.File, fn.Line))
+	}
+	strBuilder.WriteString("---\n")
+	return strBuilder.String()
+}
@@ -0,0 +1,72 @@
+package code
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadResult(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a_test.go.yaml")
+	content := `file_description: 第一段
+file_info:
+  package_name: svc
+  imports: [fmt]
+---
+file_description: 第二段
+file_info:
+  package_name: svc
+  imports: [testing]
+tested_behaviors:
+  - test: TestA
+    behavior: 校验 A
+
+module_path: example.com/m
+import_path: example.com/m/svc
+
+test_targets:
+  TestA: [A]
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := LoadResult(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ImportPath != "example.com/m/svc" || len(result.FileInfo.Imports) != 2 {
+		t.Errorf("unexpected merged result: %+v", result)
+	}
+	entry := FormatSummaryEntry("svc/a_test.go", &result)
+	if !strings.Contains(entry, "测试: TestA 校验 A (被测函数: A)") {
+		t.Errorf("entry missing test targets:\n%s", entry)
+	}
+}
+
+func TestRenderSummaryOrder(t *testing.T) {
+	entries := []*SummaryEntry{
+		{Path: "z/b.go", Result: ParsedYAML{ImportPath: "example.com/m/a"}},
+		{Path: "b/x.py", Result: ParsedYAML{Language: LanguagePython}},
+		{Path: "z/a.go", Result: ParsedYAML{ImportPath: "example.com/m/a"}},
+	}
+	meta := SummaryMeta{Root: "/src", Commit: "abc", Model: "m", PromptVersion: "1", Date: time.Unix(0, 0).UTC()}
+	summary := RenderSummary(meta, entries, []*UntestedFunc{{Name: "A", File: "z/a.go", Line: 3}})
+
+	if !strings.HasPrefix(summary, "# 代码分析总结\n根目录: /src\n提交: abc\n") {
+		t.Errorf("unexpected header:\n%s", summary)
+	}
+	order := []string{"文件名: b/x.py", "文件名: z/a.go", "文件名: z/b.go", "- A (z/a.go:3)"}
+	last := -1
+	for _, s := range order {
+		i := strings.Index(summary, s)
+		if i <= last {
+			t.Fatalf("%q out of order in:\n%s", s, summary)
+		}
+		last = i
+	}
+	if again := RenderSummary(meta, entries, []*UntestedFunc{{Name: "A", File: "z/a.go", Line: 3}}); again != summary {
+		t.Error("render is not deterministic")
+	}
+}
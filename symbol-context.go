@@ -0,0 +1,336 @@
+package code
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SmallFileLines 不超过该行数的文件在问答时直接发送全文
+const SmallFileLines = 150
+
+// SymbolSnippet 选中的函数或类型的源码
+type SymbolSnippet struct {
+	Name      string
+	File      string
+	StartLine int
+	EndLine   int
+	Source    string
+}
+
+// SymbolContext 问答时代替整个文件发送的内容：选中符号的源码、文件中其他声明的签名以及同一个包中的调用方和被调用方
+type SymbolContext struct {
+	File     string
+	Snippets []*SymbolSnippet
+	// Signatures 文件中未选中的顶层声明
+	Signatures []string
+	// Callers 同一个包中调用选中函数的函数，例如 client.go:42 (*Client).Do
+	Callers []string
+	// Callees 选中函数调用的同一个包中的函数签名
+	Callees []string
+	// External 选中函数调用的其他包或无法解析的函数
+	External []string
+}
+
+// packageDecl 包中的函数声明
+type packageDecl struct {
+	file string
+	fset *token.FileSet
+	src  []byte
+	fn   *ast.FuncDecl
+	// imports 所在文件导入的包名
+	imports map[string]bool
+}
+
+// ExtractSymbolContext 从 Go 文件中提取 names 指定的函数、方法或类型，names 支持 Func、Type、Type.Method 和 (*Type).Method
+//
+// 没有找到任何符号时返回的 Snippets 为空
+func ExtractSymbolContext(path string, names []string) (*SymbolContext, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[normalizeSymbolName(name)] = true
+	}
+
+	ctx := &SymbolContext{File: path}
+	var selected []*ast.FuncDecl
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if wanted[funcSymbolName(d)] {
+				ctx.Snippets = append(ctx.Snippets, newSnippet(fset, src, path, funcSymbolName(d), d.Doc, d))
+				selected = append(selected, d)
+			} else {
+				ctx.Signatures = append(ctx.Signatures, funcSignature(fset, src, d))
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				if wanted[typeSpec.Name.Name] {
+					doc := typeSpec.Doc
+					var node ast.Node = typeSpec
+					// 单独声明的类型包含 type 关键字和文档注释
+					if len(d.Specs) == 1 {
+						doc, node = d.Doc, d
+					}
+					ctx.Snippets = append(ctx.Snippets, newSnippet(fset, src, path, typeSpec.Name.Name, doc, node))
+				} else {
+					ctx.Signatures = append(ctx.Signatures, "type "+typeSpec.Name.Name+" "+typeKind(typeSpec.Type))
+				}
+			}
+		}
+	}
+	if len(selected) > 0 {
+		ctx.collectCallGraph(filepath.Dir(path), selected, importNames(f))
+	}
+	return ctx, nil
+}
+
+// collectCallGraph 在同一目录的 Go 文件中查找选中函数的调用方和被调用方
+func (ctx *SymbolContext) collectCallGraph(dir string, selected []*ast.FuncDecl, imports map[string]bool) {
+	decls := loadPackageDecls(dir)
+	byName := make(map[string][]*packageDecl)
+	for _, decl := range decls {
+		byName[decl.fn.Name.Name] = append(byName[decl.fn.Name.Name], decl)
+	}
+
+	selectedNames := make(map[string]bool)
+	for _, fn := range selected {
+		selectedNames[fn.Name.Name] = true
+	}
+
+	// 被调用方：按函数名的最后一段匹配同一个包中的函数
+	seen := make(map[string]bool)
+	for _, fn := range selected {
+		for _, call := range funcCalls(fn) {
+			name := call[strings.LastIndex(call, ".")+1:]
+			if seen[call] || selectedNames[name] {
+				continue
+			}
+			seen[call] = true
+			if matches := byName[name]; len(matches) > 0 && isLocalCall(call, imports) {
+				for _, decl := range matches {
+					ctx.Callees = append(ctx.Callees, funcSignature(decl.fset, decl.src, decl.fn))
+				}
+				continue
+			}
+			ctx.External = append(ctx.External, call)
+		}
+	}
+
+	// 调用方
+	for _, decl := range decls {
+		if selectedNames[decl.fn.Name.Name] && decl.file == filepath.Clean(ctx.File) {
+			continue
+		}
+		for _, call := range funcCalls(decl.fn) {
+			name := call[strings.LastIndex(call, ".")+1:]
+			if selectedNames[name] && isLocalCall(call, decl.imports) {
+				line := decl.fset.Position(decl.fn.Pos()).Line
+				ctx.Callers = append(ctx.Callers, fmt.Sprintf("%s:%d %s", filepath.Base(decl.file), line, funcDeclName(decl.fn)))
+				break
+			}
+		}
+	}
+	ctx.Callees = uniqueStrings(ctx.Callees)
+	sort.Strings(ctx.External)
+}
+
+// isLocalCall 判断调用是否可能是包内的函数或方法：不带限定符，或者限定符不是导入的包
+func isLocalCall(call string, imports map[string]bool) bool {
+	i := strings.LastIndex(call, ".")
+	return i < 0 || !imports[call[:i]]
+}
+
+// importNames 文件导入的包在代码中使用的名称
+func importNames(f *ast.File) map[string]bool {
+	names := make(map[string]bool)
+	for _, imp := range f.Imports {
+		if imp.Name != nil {
+			names[imp.Name.Name] = true
+			continue
+		}
+		parts := strings.Split(strings.Trim(imp.Path.Value, `"`), "/")
+		name := parts[len(parts)-1]
+		// example.com/foo/v2 的包名通常为 foo
+		if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+			name = parts[len(parts)-2]
+		}
+		names[strings.ReplaceAll(name, "-", "")] = true
+	}
+	return names
+}
+
+// loadPackageDecls 解析目录中的非测试 Go 文件并返回所有函数声明，解析失败的文件被忽略
+func loadPackageDecls(dir string) []*packageDecl {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	var decls []*packageDecl
+	for _, path := range paths {
+		if IsTestFile(path) {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, path, src, 0)
+		if err != nil {
+			continue
+		}
+		imports := importNames(f)
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				decls = append(decls, &packageDecl{file: path, fset: fset, src: src, fn: fn, imports: imports})
+			}
+		}
+	}
+	return decls
+}
+
+// funcCalls 函数体中调用的函数名，按出现顺序去重
+func funcCalls(fn *ast.FuncDecl) []string {
+	if fn.Body == nil {
+		return nil
+	}
+	var calls []string
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if name := callName(call.Fun); name != "" {
+				calls = append(calls, name)
+			}
+		}
+		return true
+	})
+	return uniqueStrings(calls)
+}
+
+func newSnippet(fset *token.FileSet, src []byte, path, name string, doc *ast.CommentGroup, node ast.Node) *SymbolSnippet {
+	start := node.Pos()
+	if doc != nil {
+		start = doc.Pos()
+	}
+	startPos, endPos := fset.Position(start), fset.Position(node.End())
+	return &SymbolSnippet{
+		Name:      name,
+		File:      path,
+		StartLine: startPos.Line,
+		EndLine:   endPos.Line,
+		Source:    string(src[startPos.Offset:endPos.Offset]),
+	}
+}
+
+// funcSignature 函数声明去掉函数体后的源码
+func funcSignature(fset *token.FileSet, src []byte, fn *ast.FuncDecl) string {
+	end := fn.End()
+	if fn.Body != nil {
+		end = fn.Body.Lbrace
+	}
+	return strings.TrimSpace(string(src[fset.Position(fn.Pos()).Offset:fset.Position(end).Offset]))
+}
+
+// funcSymbolName 函数的符号名，方法为 Type.Method，泛型接收者去掉类型参数，例如 List[T].Push -> List.Push
+func funcSymbolName(fn *ast.FuncDecl) string {
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		return receiverIdent(fn.Recv.List[0].Type) + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// receiverIdent 接收者的类型名，去掉指针和类型参数
+func receiverIdent(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverIdent(t.X)
+	case *ast.IndexExpr:
+		return receiverIdent(t.X)
+	case *ast.IndexListExpr:
+		return receiverIdent(t.X)
+	case *ast.ParenExpr:
+		return receiverIdent(t.X)
+	}
+	return exprToString(expr)
+}
+
+// normalizeSymbolName 把 (*Type).Method、(t *Type) Method、List[T].Push 以及带签名的写法统一为 Type.Method
+func normalizeSymbolName(name string) string {
+	name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "func "))
+	// 去掉类型参数，例如 List[T].Push
+	for {
+		start := strings.Index(name, "[")
+		end := strings.Index(name, "]")
+		if start < 0 || end < start {
+			break
+		}
+		name = name[:start] + name[end+1:]
+	}
+	// 接收者写法：(*Type).Method 或 (t *Type) Method
+	if strings.HasPrefix(name, "(") {
+		if end := strings.Index(name, ")"); end > 0 {
+			fields := strings.Fields(name[1:end])
+			rest := strings.TrimLeft(name[end+1:], ". \t")
+			if len(fields) > 0 && rest != "" {
+				name = fields[len(fields)-1] + "." + rest
+			}
+		}
+	}
+	// 去掉签名部分，例如 ParseByFile(filePath string)
+	if i := strings.IndexAny(name, "( \t"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimLeft(name, "*")
+}
+
+func typeKind(expr ast.Expr) string {
+	switch expr.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	default:
+		return exprToString(expr)
+	}
+}
+
+// Format 问答提示词中使用的文本
+func (ctx *SymbolContext) Format() string {
+	var strBuilder strings.Builder
+	for _, snippet := range ctx.Snippets {
+		strBuilder.WriteString(fmt.Sprintf("// %s (第 %d-%d 行)\n", snippet.Name, snippet.StartLine, snippet.EndLine))
+		strBuilder.WriteString(NumberLines(snippet.Source, snippet.StartLine))
+		strBuilder.WriteString("\n")
+	}
+	writeSection := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		strBuilder.WriteString(title + ":\n")
+		for _, line := range lines {
+			strBuilder.WriteString("- " + line + "\n")
+		}
+	}
+	writeSection("调用方", ctx.Callers)
+	writeSection("调用的包内函数", ctx.Callees)
+	if len(ctx.External) > 0 {
+		strBuilder.WriteString("调用的其他函数: " + strings.Join(ctx.External, ", ") + "\n")
+	}
+	writeSection("文件中的其他声明", ctx.Signatures)
+	return strBuilder.String()
+}
@@ -0,0 +1,109 @@
+package code
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractSymbolContext(t *testing.T) {
+	dir := t.TempDir()
+	client := `package svc
+
+import "fmt"
+
+// Client 带重试的客户端
+type Client struct {
+	retries int
+}
+
+// Do 发送请求，失败时重试
+func (c *Client) Do(req string) error {
+	for i := 0; i < c.retries; i++ {
+		if err := send(req); err == nil {
+			return nil
+		}
+		wait(i)
+	}
+	return fmt.Errorf("failed after %d retries", c.retries)
+}
+
+func send(req string) error { return nil }
+
+func wait(attempt int) {}
+`
+	caller := `package svc
+
+func Run(c *Client) error {
+	return c.Do("ping")
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "client.go"), []byte(client), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "run.go"), []byte(caller), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := ExtractSymbolContext(filepath.Join(dir, "client.go"), []string{"(*Client).Do"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ctx.Snippets) != 1 {
+		t.Fatalf("snippets = %+v", ctx.Snippets)
+	}
+	snippet := ctx.Snippets[0]
+	if snippet.Name != "Client.Do" || snippet.StartLine != 10 || snippet.EndLine != 19 || !strings.HasPrefix(snippet.Source, "// Do 发送请求") {
+		t.Errorf("unexpected snippet: %+v", snippet)
+	}
+	if want := []string{"run.go:3 Run"}; !reflect.DeepEqual(ctx.Callers, want) {
+		t.Errorf("callers = %v, want %v", ctx.Callers, want)
+	}
+	if want := []string{"func send(req string) error", "func wait(attempt int)"}; !reflect.DeepEqual(ctx.Callees, want) {
+		t.Errorf("callees = %v, want %v", ctx.Callees, want)
+	}
+	if want := []string{"fmt.Errorf"}; !reflect.DeepEqual(ctx.External, want) {
+		t.Errorf("external = %v, want %v", ctx.External, want)
+	}
+	if want := []string{"type Client struct", "func send(req string) error", "func wait(attempt int)"}; !reflect.DeepEqual(ctx.Signatures, want) {
+		t.Errorf("signatures = %v, want %v", ctx.Signatures, want)
+	}
+
+	ctx, err = ExtractSymbolContext(filepath.Join(dir, "client.go"), []string{"Missing"})
+	if err != nil || len(ctx.Snippets) != 0 {
+		t.Errorf("expected no snippets, got %+v, %v", ctx.Snippets, err)
+	}
+}
+
+func TestNormalizeSymbolName(t *testing.T) {
+	cases := map[string]string{
+		"ParseByFile":                          "ParseByFile",
+		"ParseByFile(filePath string)":         "ParseByFile",
+		"func ParseByFile(filePath string)":    "ParseByFile",
+		"(*Parser).ParseByFile":                "Parser.ParseByFile",
+		"(p *Parser) ParseByFile(path string)": "Parser.ParseByFile",
+		"*Parser.ParseByFile":                  "Parser.ParseByFile",
+		"List[T].Push":                         "List.Push",
+		"(*List[K, V]).Push(v V)":              "List.Push",
+	}
+	for name, want := range cases {
+		if got := normalizeSymbolName(name); got != want {
+			t.Errorf("normalizeSymbolName(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "list.go")
+	src := "package list\n\ntype List[T any] struct{ items []T }\n\nfunc (l *List[T]) Push(v T) { l.items = append(l.items, v) }\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := ExtractSymbolContext(path, []string{"List.Push", "Push(v T)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ctx.Snippets) != 1 || ctx.Snippets[0].Name != "List.Push" {
+		t.Errorf("snippets = %+v", ctx.Snippets)
+	}
+}
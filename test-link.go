@@ -0,0 +1,176 @@
+package code
+
+import (
+	"go/ast"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 测试函数名的前缀
+var testFuncPrefixes = []string{"Test", "Benchmark", "Fuzz", "Example"}
+
+// TestLink 测试函数与其覆盖的生产代码函数
+type TestLink struct {
+	Test    string   `json:"test" yaml:"test"`
+	File    string   `json:"file" yaml:"file"`
+	Line    int      `json:"line" yaml:"line"`
+	Targets []string `json:"targets" yaml:"targets"`
+}
+
+// UntestedFunc 没有任何测试函数调用的导出函数
+type UntestedFunc struct {
+	Name string `json:"name" yaml:"name"`
+	File string `json:"file" yaml:"file"`
+	Line int    `json:"line" yaml:"line"`
+}
+
+// TestCoverage 测试与生产代码的关联结果
+type TestCoverage struct {
+	Links    []*TestLink     `json:"links" yaml:"links"`
+	Untested []*UntestedFunc `json:"untested" yaml:"untested"`
+}
+
+// IsTestFile 判断是否为 Go 测试文件
+func IsTestFile(path string) bool {
+	return strings.HasSuffix(path, "_test.go")
+}
+
+// isTestFunc 判断函数是否为 go test 识别的测试、基准、模糊测试或示例函数
+func isTestFunc(m *FuncMetrics) bool {
+	if m.Receiver != "" {
+		return false
+	}
+	for _, prefix := range testFuncPrefixes {
+		if !strings.HasPrefix(m.Name, prefix) {
+			continue
+		}
+		// TestXxx 中前缀之后不能是小写字母
+		rest := m.Name[len(prefix):]
+		if r, _ := utf8.DecodeRuneInString(rest); rest == "" || !unicode.IsLower(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkTests 把测试函数关联到同一目录下被调用或按名称对应的生产代码函数，并找出没有测试覆盖的导出函数
+//
+// 关联规则:
+//   - 测试函数体内(包括 t.Run 子测试)直接调用的函数或方法，按函数名匹配
+//   - 测试函数名去掉前缀后按 _ 拆分，例如 TestParser_ParseByFile 关联 Parser 的 ParseByFile 方法
+func LinkTests(production, tests []*ParseResult) *TestCoverage {
+	// 目录 -> 函数名 -> 该目录下同名的函数和方法
+	index := make(map[string]map[string][]*FuncMetrics)
+	for _, result := range production {
+		for _, m := range result.Funcs {
+			dir := filepath.Dir(m.File)
+			if index[dir] == nil {
+				index[dir] = make(map[string][]*FuncMetrics)
+			}
+			index[dir][m.Name] = append(index[dir][m.Name], m)
+		}
+	}
+
+	coverage := &TestCoverage{}
+	tested := make(map[*FuncMetrics]bool)
+	for _, result := range tests {
+		calls := make(map[string][]string)
+		for _, facts := range result.FuncFacts {
+			calls[facts.Name] = facts.Calls
+		}
+		for _, m := range result.Funcs {
+			if !isTestFunc(m) {
+				continue
+			}
+			funcs := index[filepath.Dir(m.File)]
+			targets := make(map[*FuncMetrics]bool)
+			for _, call := range calls[m.Name] {
+				// pkg.Func 或 obj.Method 只取最后一段
+				name := call[strings.LastIndex(call, ".")+1:]
+				for _, target := range funcs[name] {
+					targets[target] = true
+				}
+			}
+			for _, target := range linkTestByName(m.Name, funcs) {
+				targets[target] = true
+			}
+
+			link := &TestLink{Test: m.Name, File: m.File, Line: m.Line}
+			for target := range targets {
+				tested[target] = true
+				link.Targets = append(link.Targets, target.FullName())
+			}
+			sort.Strings(link.Targets)
+			coverage.Links = append(coverage.Links, link)
+		}
+	}
+
+	for _, result := range production {
+		for _, m := range result.Funcs {
+			if !ast.IsExported(m.Name) || tested[m] || (m.Receiver != "" && !ast.IsExported(strings.TrimPrefix(m.Receiver, "*"))) {
+				continue
+			}
+			coverage.Untested = append(coverage.Untested, &UntestedFunc{Name: m.FullName(), File: m.File, Line: m.Line})
+		}
+	}
+
+	sort.Slice(coverage.Links, func(i, j int) bool {
+		if coverage.Links[i].File != coverage.Links[j].File {
+			return coverage.Links[i].File < coverage.Links[j].File
+		}
+		return coverage.Links[i].Line < coverage.Links[j].Line
+	})
+	sort.Slice(coverage.Untested, func(i, j int) bool {
+		if coverage.Untested[i].File != coverage.Untested[j].File {
+			return coverage.Untested[i].File < coverage.Untested[j].File
+		}
+		return coverage.Untested[i].Line < coverage.Untested[j].Line
+	})
+	return coverage
+}
+
+// linkTestByName 按测试函数名匹配生产代码函数，首字母大小写均可匹配
+func linkTestByName(testName string, funcs map[string][]*FuncMetrics) []*FuncMetrics {
+	for _, prefix := range testFuncPrefixes {
+		if strings.HasPrefix(testName, prefix) {
+			testName = testName[len(prefix):]
+			break
+		}
+	}
+	parts := strings.Split(testName, "_")
+
+	var targets []*FuncMetrics
+	// TestType_Method 形式优先匹配该类型的方法
+	if len(parts) >= 2 {
+		for _, m := range candidates(funcs, parts[1]) {
+			if strings.TrimPrefix(m.Receiver, "*") == parts[0] {
+				targets = append(targets, m)
+			}
+		}
+		if len(targets) > 0 {
+			return targets
+		}
+	}
+	for _, m := range candidates(funcs, parts[0]) {
+		if m.Receiver == "" {
+			targets = append(targets, m)
+		}
+	}
+	return targets
+}
+
+func candidates(funcs map[string][]*FuncMetrics, name string) []*FuncMetrics {
+	if name == "" {
+		return nil
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	lower := string(unicode.ToLower(r)) + name[size:]
+	upper := string(unicode.ToUpper(r)) + name[size:]
+	if lower == upper {
+		return funcs[name]
+	}
+	return append(append([]*FuncMetrics{}, funcs[upper]...), funcs[lower]...)
+}
@@ -0,0 +1,78 @@
+package code
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLinkTests(t *testing.T) {
+	prodSrc := `package demo
+
+type Store struct{}
+
+func NewStore() *Store { return &Store{} }
+
+func (s *Store) Add(item string) error { return nil }
+
+func (s *Store) Remove(item string) {}
+
+func Format(item string) string { return item }
+
+func helper() {}
+`
+	testSrc := `package demo
+
+import "testing"
+
+func TestStore(t *testing.T) {
+	s := NewStore()
+	t.Run("add", func(t *testing.T) {
+		if err := s.Add("a"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestStore_Remove(t *testing.T) {}
+
+func Testhelper(t *testing.T) {}
+`
+	dir := t.TempDir()
+	prodPath := filepath.Join(dir, "store.go")
+	testPath := filepath.Join(dir, "store_test.go")
+	if err := os.WriteFile(prodPath, []byte(prodSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(testPath, []byte(testSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewParser()
+	prod, err := parser.ParseByFile(prodPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests, err := parser.ParseByFile(testPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coverage := LinkTests([]*ParseResult{prod}, []*ParseResult{tests})
+	links := make(map[string][]string)
+	for _, link := range coverage.Links {
+		links[link.Test] = link.Targets
+	}
+	want := map[string][]string{
+		"TestStore":        {"(*Store).Add", "NewStore"},
+		"TestStore_Remove": {"(*Store).Remove"},
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("links = %v, want %v", links, want)
+	}
+
+	if len(coverage.Untested) != 1 || coverage.Untested[0].Name != "Format" {
+		t.Errorf("untested = %+v, want only Format", coverage.Untested)
+	}
+}
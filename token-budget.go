@@ -0,0 +1,217 @@
+package code
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// modelContextWindows 模型的上下文窗口(token)
+var modelContextWindows = map[string]int{
+	openai.GPT4oMini:     128000,
+	openai.GPT4o:         128000,
+	openai.GPT4Turbo:     128000,
+	openai.GPT4:          8192,
+	openai.GPT3Dot5Turbo: 16385,
+}
+
+const (
+	// defaultContextWindow 未知模型使用的上下文窗口
+	defaultContextWindow = 16385
+	// answerReserveTokens 为模型的回复预留的 token
+	answerReserveTokens = 4096
+	// minTruncateTokens 剩余预算少于该值时直接省略内容，不再截断
+	minTruncateTokens = 200
+)
+
+// truncatedMark 截断的内容末尾的标记
+const truncatedMark = "\n...(内容过长，已截断)\n"
+
+// ContextWindow 模型的上下文窗口，未知模型返回 defaultContextWindow
+func ContextWindow(model string) int {
+	if window, ok := modelContextWindows[model]; ok {
+		return window
+	}
+	return defaultContextWindow
+}
+
+// EstimateTokens 不依赖分词器估算文本的 token 数：ASCII 字符约 4 个一个 token，其他字符(中文等)按每个字符一个 token 计算
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < 128 {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// TruncateTokens 把文本截断到大约 tokens 个 token，尽量在换行处截断并加上截断标记
+func TruncateTokens(text string, tokens int) string {
+	if EstimateTokens(text) <= tokens {
+		return text
+	}
+	limit := tokens - EstimateTokens(truncatedMark)
+	ascii, other, end := 0, 0, 0
+	for i, r := range text {
+		if r < 128 {
+			ascii++
+		} else {
+			other++
+		}
+		if (ascii+3)/4+other > limit {
+			break
+		}
+		end = i + len(string(r))
+	}
+	cut := text[:end]
+	if i := strings.LastIndex(cut, "\n"); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return cut + truncatedMark
+}
+
+// BudgetSection 按预算分配的一段内容
+type BudgetSection struct {
+	// Name 报告省略内容时使用的名称，例如文件路径
+	Name string
+	Text string
+	// Summary 预算不足时代替全文的简短版本，为空时直接截断或省略
+	Summary string
+}
+
+// TokenBudget 一次请求可以使用的 token 预算
+type TokenBudget struct {
+	// Limit 提示词最多可以使用的 token，已经扣除为回复预留的部分
+	Limit int
+}
+
+// NewTokenBudget 根据模型的上下文窗口创建预算
+func NewTokenBudget(model string) *TokenBudget {
+	return &TokenBudget{Limit: ContextWindow(model) - answerReserveTokens}
+}
+
+// Fit 在扣除固定部分(提示词模板、问题等)之后，把按重要性从高到低排列的内容放入预算：
+//  1. 从排名最低的内容开始依次换成简短版本
+//  2. 仍然超出时从排名最低的内容开始依次省略，剩余的预算足够时把最后省略的内容截断后保留
+//
+// 返回保留的内容(顺序不变)和被缩减的内容说明
+func (b *TokenBudget) Fit(fixed string, sections []BudgetSection) ([]string, []string) {
+	available := b.Limit - EstimateTokens(fixed)
+	texts := make([]string, len(sections))
+	tokens := make([]int, len(sections))
+	notes := make([]string, len(sections))
+	used := 0
+	for i, section := range sections {
+		texts[i] = section.Text
+		tokens[i] = EstimateTokens(section.Text)
+		used += tokens[i]
+	}
+
+	for i := len(sections) - 1; i >= 0 && used > available; i-- {
+		if sections[i].Summary == "" {
+			continue
+		}
+		summaryTokens := EstimateTokens(sections[i].Summary)
+		if summaryTokens >= tokens[i] {
+			continue
+		}
+		used += summaryTokens - tokens[i]
+		texts[i], tokens[i], notes[i] = sections[i].Summary, summaryTokens, "只保留摘要"
+	}
+
+	last := -1
+	for i := len(sections) - 1; i >= 0 && used > available; i-- {
+		used -= tokens[i]
+		texts[i], notes[i], last = "", "已省略", i
+	}
+	if last >= 0 && available-used >= minTruncateTokens {
+		texts[last], notes[last] = TruncateTokens(sections[last].Text, available-used), "已截断"
+	}
+
+	var kept, dropped []string
+	for i, section := range sections {
+		if texts[i] != "" {
+			kept = append(kept, texts[i])
+		}
+		if notes[i] != "" {
+			dropped = append(dropped, section.Name+": "+notes[i])
+		}
+	}
+	return kept, dropped
+}
+
+// FitText 把单段文本放入预算，超出时截断
+func (b *TokenBudget) FitText(fixed, name, text string) (string, []string) {
+	available := b.Limit - EstimateTokens(fixed)
+	if EstimateTokens(text) <= available {
+		return text, nil
+	}
+	return TruncateTokens(text, max(available, minTruncateTokens)), []string{name + ": 已截断"}
+}
+
+// SummarySections 把总结信息按 "---" 拆分为条目，每个文件条目的简短版本只保留文件名和功能
+func SummarySections(summary string) []BudgetSection {
+	var sections []BudgetSection
+	for _, block := range strings.SplitAfter(summary, "---\n") {
+		if strings.TrimSpace(block) == "" {
+			continue
+		}
+		section := BudgetSection{Name: "总结信息", Text: block}
+		lines := strings.Split(block, "\n")
+		if name, ok := strings.CutPrefix(lines[0], "文件名: "); ok {
+			section.Name = name + " 的总结"
+			if len(lines) > 2 && strings.HasPrefix(lines[1], "功能: ") {
+				section.Summary = lines[0] + "\n" + lines[1] + "\n---\n"
+			}
+		}
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+// RankSections 使用 BM25 按与问题的相关性重新排列内容，命中问题的内容排在前面，
+// 其余内容保持原来的顺序，预算不足时最先被缩减
+func RankSections(sections []BudgetSection, question string) []BudgetSection {
+	index := &SearchIndex{}
+	for i, section := range sections {
+		doc := &SearchDoc{Path: fmt.Sprintf("%06d", i), Terms: make(map[string]int)}
+		for _, term := range SearchTokens(section.Text) {
+			doc.Terms[term]++
+			doc.Length++
+		}
+		index.Docs = append(index.Docs, doc)
+	}
+	ranked := make([]BudgetSection, 0, len(sections))
+	matched := make(map[string]bool)
+	for _, hit := range index.Search(question, 0) {
+		i, _ := strconv.Atoi(hit.Path)
+		matched[hit.Path] = true
+		ranked = append(ranked, sections[i])
+	}
+	for i, section := range sections {
+		if !matched[index.Docs[i].Path] {
+			ranked = append(ranked, section)
+		}
+	}
+	return ranked
+}
+
+// RankSummary 按与问题的相关性重新排列完整总结文件中的条目(包括未被测试的导出函数一节)，开头的总结信息保持在最前面，
+// 没有选出候选文件时使用，使预算不足时先缩减与问题无关的条目
+func RankSummary(summary, question string) string {
+	sections := SummarySections(summary)
+	var head []BudgetSection
+	if len(sections) > 0 && strings.HasPrefix(sections[0].Text, "# ") {
+		head, sections = sections[:1], sections[1:]
+	}
+	var strBuilder strings.Builder
+	for _, section := range append(head, RankSections(sections, question)...) {
+		strBuilder.WriteString(section.Text)
+	}
+	return strBuilder.String()
+}
@@ -0,0 +1,106 @@
+package code
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens("abcdefgh"); got != 2 {
+		t.Errorf("ascii tokens = %d, want 2", got)
+	}
+	if got := EstimateTokens("登录流程"); got != 4 {
+		t.Errorf("cjk tokens = %d, want 4", got)
+	}
+	text := strings.Repeat("line of source code\n", 200)
+	truncated := TruncateTokens(text, 100)
+	if EstimateTokens(truncated) > 100 || !strings.HasSuffix(truncated, truncatedMark) {
+		t.Errorf("truncated to %d tokens: %q", EstimateTokens(truncated), truncated[len(truncated)-40:])
+	}
+	if TruncateTokens("short", 100) != "short" {
+		t.Error("short text should not be truncated")
+	}
+}
+
+func TestTokenBudgetFit(t *testing.T) {
+	section := func(name string, tokens int, summary string) BudgetSection {
+		return BudgetSection{Name: name, Text: strings.Repeat("码", tokens), Summary: summary}
+	}
+	sections := []BudgetSection{
+		section("a.go", 300, ""),
+		section("b.go", 300, "b"),
+		section("c.go", 300, "c"),
+	}
+
+	budget := &TokenBudget{Limit: 1000}
+	kept, dropped := budget.Fit("", sections)
+	if len(kept) != 3 || dropped != nil {
+		t.Errorf("kept %d, dropped %v", len(kept), dropped)
+	}
+
+	// 先把排名最低的内容换成摘要
+	budget.Limit = 700
+	kept, dropped = budget.Fit("", sections)
+	if want := []string{"c.go: 只保留摘要"}; !reflect.DeepEqual(dropped, want) || kept[2] != "c" {
+		t.Errorf("dropped = %v, want %v", dropped, want)
+	}
+
+	// 没有摘要时省略，剩余预算足够时截断最后省略的内容
+	sections[1].Summary, sections[2].Summary = "", ""
+	budget.Limit = 550
+	kept, dropped = budget.Fit(strings.Repeat("问", 10), sections)
+	if want := []string{"b.go: 已截断", "c.go: 已省略"}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropped = %v, want %v", dropped, want)
+	}
+	if len(kept) != 2 || !strings.HasSuffix(kept[1], truncatedMark) {
+		t.Errorf("kept = %d sections", len(kept))
+	}
+	total := 10
+	for _, text := range kept {
+		total += EstimateTokens(text)
+	}
+	if total > budget.Limit {
+		t.Errorf("total tokens %d exceed limit %d", total, budget.Limit)
+	}
+}
+
+func TestSummarySections(t *testing.T) {
+	summary := "# 代码分析总结\n文件数: 2\n---\n" +
+		"文件名: a.go\n功能: 登录\n包名: auth\n---\n" +
+		"文件名: b.go\n功能: 重试\n包名: client\n---\n"
+	sections := SummarySections(summary)
+	if len(sections) != 3 {
+		t.Fatalf("sections = %d, want 3", len(sections))
+	}
+	if sections[0].Summary != "" || sections[1].Name != "a.go 的总结" {
+		t.Errorf("sections = %+v", sections[:2])
+	}
+	if want := "文件名: b.go\n功能: 重试\n---\n"; sections[2].Summary != want {
+		t.Errorf("summary = %q, want %q", sections[2].Summary, want)
+	}
+	var joined strings.Builder
+	for _, section := range sections {
+		joined.WriteString(section.Text)
+	}
+	if joined.String() != summary {
+		t.Error("sections should join back to the summary")
+	}
+}
+
+func TestRankSummary(t *testing.T) {
+	summary := "# 代码分析总结\n文件数: 2\n---\n" +
+		"文件名: a.go\n功能: 登录\n包名: auth\n---\n" +
+		"文件名: retry.go\n功能: 请求失败时重试\n包名: client\n---\n" +
+		untestedSummaryTitle + "\n- client.Retry (retry.go:10)\n---\n"
+	got := RankSummary(summary, "how does retry work")
+	if !strings.HasPrefix(got, "# 代码分析总结\n") || !strings.HasSuffix(got, "文件名: a.go\n功能: 登录\n包名: auth\n---\n") {
+		t.Errorf("unrelated entries should be ranked last:\n%s", got)
+	}
+	if len(got) != len(summary) {
+		t.Errorf("ranked summary should keep every entry:\n%s", got)
+	}
+	if got := RankSummary(summary, "unrelated"); got != summary {
+		t.Errorf("unmatched summary should keep its order:\n%s", got)
+	}
+}
@@ -0,0 +1,173 @@
+package code
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+)
+
+// VectorStoreFileName 输出目录中的向量索引
+const VectorStoreFileName = "vectors.json"
+
+// 向量索引中条目的类型
+const (
+	VectorKindFile    = "file"
+	VectorKindPackage = "package"
+	VectorKindSymbol  = "symbol"
+)
+
+// VectorItem 向量索引中的一个条目：文件、包或符号的摘要及其向量
+type VectorItem struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// Path 文件条目和符号条目所在的文件，包条目为空
+	Path string `json:"path,omitempty"`
+	// Name 包名或符号名，文件条目为空
+	Name string `json:"name,omitempty"`
+	// Hash 生成向量的文本的哈希，文本未变化时不重新生成向量
+	Hash   string    `json:"hash"`
+	Vector []float32 `json:"vector"`
+	// text 生成向量的文本，不保存
+	text string
+}
+
+// VectorHit 向量检索结果
+type VectorHit struct {
+	*VectorItem
+	Score float64
+}
+
+// VectorStore 向量存储，可替换为外部的向量数据库
+type VectorStore interface {
+	// Get 按 ID 获取条目，不存在时返回 nil
+	Get(id string) *VectorItem
+	// Upsert 新增或替换条目
+	Upsert(items ...*VectorItem)
+	// Retain 只保留指定 ID 的条目
+	Retain(ids map[string]bool)
+	// Search 返回与向量余弦相似度最高的 k 个条目，kinds 不为空时只检索这些类型
+	Search(vector []float32, k int, kinds ...string) []*VectorHit
+}
+
+// FileVectorStore 保存在单个 JSON 文件中的向量存储，检索时遍历全部条目
+type FileVectorStore struct {
+	path  string
+	Items map[string]*VectorItem `json:"items"`
+}
+
+// LoadFileVectorStore 读取向量存储，文件不存在时返回空的存储
+func LoadFileVectorStore(path string) (*FileVectorStore, error) {
+	store := &FileVectorStore{path: path, Items: make(map[string]*VectorItem)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, err
+	}
+	if store.Items == nil {
+		store.Items = make(map[string]*VectorItem)
+	}
+	return store, nil
+}
+
+// Len 条目数量
+func (s *FileVectorStore) Len() int {
+	return len(s.Items)
+}
+
+func (s *FileVectorStore) Get(id string) *VectorItem {
+	return s.Items[id]
+}
+
+func (s *FileVectorStore) Upsert(items ...*VectorItem) {
+	for _, item := range items {
+		s.Items[item.ID] = item
+	}
+}
+
+func (s *FileVectorStore) Retain(ids map[string]bool) {
+	for id := range s.Items {
+		if !ids[id] {
+			delete(s.Items, id)
+		}
+	}
+}
+
+func (s *FileVectorStore) Search(vector []float32, k int, kinds ...string) []*VectorHit {
+	var hits []*VectorHit
+	for _, item := range s.Items {
+		if len(kinds) > 0 && !containsString(kinds, item.Kind) {
+			continue
+		}
+		hits = append(hits, &VectorHit{VectorItem: item, Score: CosineSimilarity(vector, item.Vector)})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if k > 0 && len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// Save 写入向量存储
+func (s *FileVectorStore) Save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// CosineSimilarity 两个向量的余弦相似度，长度不同或包含零向量时为 0
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// FuseRankings 使用倒数排名融合(RRF)合并多个排序结果，出现在多个排序中且排名靠前的条目得分更高
+func FuseRankings(rankings ...[]string) []string {
+	const k = 60
+	scores := make(map[string]float64)
+	var keys []string
+	for _, ranking := range rankings {
+		for rank, key := range ranking {
+			if _, ok := scores[key]; !ok {
+				keys = append(keys, key)
+			}
+			scores[key] += 1 / float64(k+rank+1)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return scores[keys[i]] > scores[keys[j]]
+	})
+	return keys
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package code
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// keywordEmbedder 按是否包含关键词生成向量，用于测试
+type keywordEmbedder struct {
+	keywords []string
+	calls    int
+}
+
+func (e *keywordEmbedder) Embed(texts []string) ([][]float32, error) {
+	e.calls += len(texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(e.keywords))
+		for j, keyword := range e.keywords {
+			if strings.Contains(text, keyword) {
+				vectors[i][j] = 1
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func TestEmbedItemsAndRetrieve(t *testing.T) {
+	entries := []*SummaryEntry{
+		{Path: "client/retry.go", Result: ParsedYAML{FunctionDescription: "失败后退避"}},
+		{Path: "auth/login.go", Result: ParsedYAML{FunctionDescription: "用户登录"}},
+	}
+	symbols := map[string]*ParseResult{
+		"client/retry.go": {ExportedFunc: []string{"Backoff(n int) (time.Duration)"}},
+	}
+	embedder := &keywordEmbedder{keywords: []string{"退避", "登录"}}
+	path := filepath.Join(t.TempDir(), VectorStoreFileName)
+	store, err := LoadFileVectorStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := BuildVectorItems(entries, nil, symbols)
+	if n, err := EmbedItems(store, items, embedder); err != nil || n != 3 {
+		t.Fatalf("embedded %d items, err %v, want 3", n, err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 文本未变化的条目复用已有的向量，删除的文件同时删除向量
+	store, err = LoadFileVectorStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := EmbedItems(store, BuildVectorItems(entries[:1], nil, symbols), embedder); err != nil || n != 0 {
+		t.Fatalf("re-embedded %d items, err %v, want 0", n, err)
+	}
+	if store.Len() != 2 {
+		t.Errorf("store has %d items, want 2", store.Len())
+	}
+	EmbedItems(store, items, embedder)
+
+	retriever := &Retriever{Keyword: BuildSearchIndex(entries, symbols), Vectors: store, Embedder: embedder}
+	retrieval, err := retriever.Retrieve("退避", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !retrieval.Semantic || !reflect.DeepEqual(retrieval.Files, []string{"client/retry.go"}) {
+		t.Errorf("files = %v, semantic %v", retrieval.Files, retrieval.Semantic)
+	}
+	if len(retrieval.Symbols) != 1 || retrieval.Symbols[0].Name != "Backoff" {
+		t.Errorf("symbols = %+v", retrieval.Symbols)
+	}
+}
+
+func TestFuseRankings(t *testing.T) {
+	got := FuseRankings([]string{"a", "b", "c"}, []string{"c", "b", "d"})
+	if want := []string{"c", "b", "a", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FuseRankings = %v, want %v", got, want)
+	}
+}
@@ -1,49 +1,424 @@
 package code
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
 )
 
-// 忽略的目录列表
-var ignoredDirs = []string{"vendor", "testdata", ".git", "test/", "mocks/"}
+// 忽略的目录列表，按目录名完全匹配
+var ignoredDirs = []string{"vendor", "testdata", ".git", "test", "mocks"}
+
+// IgnoreFileName 项目自定义的忽略文件，语法与 .gitignore 相同
+const IgnoreFileName = ".codeanalysisignore"
+
+// ignoreRule .gitignore 中的一条规则
+type ignoreRule struct {
+	// base 规则所在目录，相对于遍历根目录
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+	source   string
+}
+
+func (r *ignoreRule) match(relPath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	rel := relPath
+	if r.base != "" {
+		if !strings.HasPrefix(relPath, r.base+"/") {
+			return false
+		}
+		rel = strings.TrimPrefix(relPath, r.base+"/")
+	}
+	pattern := r.pattern
+	if !r.anchored {
+		pattern = "**/" + pattern
+	}
+	ok, _ := doublestar.Match(pattern, rel)
+	return ok
+}
+
+// parseIgnoreFile 读取 .gitignore 格式的文件，文件不存在时返回空
+func parseIgnoreFile(path, base string) []*ignoreRule {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
 
-func isIgnored(dir string) bool {
-	// 获取目录名称
-	dirName := filepath.Base(dir)
-	for _, ignored := range ignoredDirs {
-		if dirName == ignored {
-			return true
+	var rules []*ignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := &ignoreRule{base: base, source: path}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
 		}
+		line = strings.TrimPrefix(line, "\\")
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		// 包含 / 的规则相对于 .gitignore 所在目录，否则匹配任意层级
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
 	}
-	for _, ignored := range ignoredDirs {
-		if strings.Contains(dir, ignored) {
-			//fmt.Println("Ignored:", dir, ignored)
-			return true
+	return rules
+}
+
+// FileFilter 遍历目录时的过滤规则：.gitignore、.git/info/exclude、.codeanalysisignore 以及 include/exclude 通配符
+type FileFilter struct {
+	root     string
+	includes []string
+	excludes []string
+	// rules 目录(相对路径) -> 该目录下忽略文件中的规则
+	rules map[string][]*ignoreRule
+	// Verbose 输出每个被跳过的文件及原因
+	Verbose bool
+	// SkipGenerated 跳过生成代码和第三方代码
+	SkipGenerated bool
+	// IncludeTests 保留 _test.go 测试文件
+	IncludeTests bool
+}
+
+// NewFileFilter 创建过滤器，includes/excludes 为相对于 root 的 doublestar 通配符，例如 internal/**/*.go
+func NewFileFilter(root string, includes, excludes []string) (*FileFilter, error) {
+	for _, pattern := range append(append([]string{}, includes...), excludes...) {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid glob pattern: %s", pattern)
 		}
 	}
-	return false
+	f := &FileFilter{
+		root:     root,
+		includes: includes,
+		excludes: excludes,
+		rules:    make(map[string][]*ignoreRule),
+	}
+	f.rules[""] = parseIgnoreFile(filepath.Join(root, ".git", "info", "exclude"), "")
+	return f, nil
+}
+
+// withoutIncludes 不应用 include 通配符的副本，共享已读取的忽略规则
+func (f *FileFilter) withoutIncludes() *FileFilter {
+	copied := *f
+	copied.includes = nil
+	copied.SkipGenerated = false
+	return &copied
+}
+
+// loadDir 读取目录下的 .gitignore 和 .codeanalysisignore
+func (f *FileFilter) loadDir(relDir string) {
+	if _, ok := f.rules[relDir+"/"]; ok {
+		return
+	}
+	dir := filepath.Join(f.root, filepath.FromSlash(relDir))
+	base := relDir
+	if base == "." {
+		base = ""
+	}
+	rules := parseIgnoreFile(filepath.Join(dir, ".gitignore"), base)
+	rules = append(rules, parseIgnoreFile(filepath.Join(dir, IgnoreFileName), base)...)
+	f.rules[relDir+"/"] = rules
+}
+
+// relPath 返回相对于根目录、使用 / 分隔的路径
+func (f *FileFilter) relPath(path string) string {
+	rel, err := filepath.Rel(f.root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Skip 判断文件或目录是否需要跳过，返回跳过的原因
+func (f *FileFilter) Skip(path string, isDir bool) (bool, string) {
+	rel := f.relPath(path)
+	if rel == "." {
+		f.loadDir(".")
+		return false, ""
+	}
+
+	if isDir {
+		for _, ignored := range ignoredDirs {
+			if filepath.Base(path) == ignored {
+				return true, "ignored directory " + ignored
+			}
+		}
+	}
+
+	// 按从外到内的顺序应用各级忽略文件，后面的规则覆盖前面的
+	ignored, reason := false, ""
+	apply := func(rules []*ignoreRule) {
+		for _, rule := range rules {
+			if rule.match(rel, isDir) {
+				ignored = !rule.negate
+				reason = fmt.Sprintf("%s: %s", rule.source, rule.pattern)
+			}
+		}
+	}
+	apply(f.rules[""])
+	apply(f.rules["./"])
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		apply(f.rules[strings.Join(parts[:i], "/")+"/"])
+	}
+	if ignored {
+		return true, reason
+	}
+
+	for _, pattern := range f.excludes {
+		if ok, _ := doublestar.Match(pattern, rel); ok {
+			return true, "excluded by " + pattern
+		}
+	}
+
+	if isDir {
+		f.loadDir(rel)
+		return false, ""
+	}
+
+	if len(f.includes) > 0 {
+		for _, pattern := range f.includes {
+			if ok, _ := doublestar.Match(pattern, rel); ok {
+				return false, ""
+			}
+		}
+		return true, "not matched by include patterns"
+	}
+	return false, ""
+}
+
+func (f *FileFilter) logSkip(path, reason string) {
+	if f.Verbose {
+		fmt.Printf("Skip: %s (%s)\n", path, reason)
+	}
+}
+
+// ErrSymlinkLoop 符号链接指向其所在的目录或上级目录
+var ErrSymlinkLoop = errors.New("symlink loop")
+
+// WalkOptions 遍历目录的选项
+type WalkOptions struct {
+	// Filter 过滤规则，为 nil 时使用遍历目录下的默认规则
+	Filter *FileFilter
+	// Match 需要输出的文件，为 nil 时输出 .go 文件
+	Match func(path string) bool
+	// FollowSymlinks 进入指向目录的符号链接，通过真实路径检测循环。
+	// 真实目录先于符号链接遍历，总是使用自己的路径；指向已遍历目录的符号链接直接跳过。
+	// 指向文件的符号链接总是按普通文件处理
+	FollowSymlinks bool
+	// OnError 无法读取的目录、失效的符号链接或符号链接循环，遍历会跳过该路径继续进行。
+	// 为 nil 时输出到标准输出
+	OnError func(path string, err error)
 }
 
 // WalkDir 遍历目录并输出所有的 .go 文件
 func WalkDir(dir string, callback func(path string)) error {
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	return Walk(dir, WalkOptions{}, callback)
+}
+
+// WalkDirFiltered 使用指定的过滤规则遍历目录并输出所有的 .go 文件
+func WalkDirFiltered(dir string, filter *FileFilter, callback func(path string)) error {
+	return Walk(dir, WalkOptions{Filter: filter}, callback)
+}
+
+// WalkProtoDir 遍历目录并输出所有的 .proto 文件，忽略 opts.Match
+func WalkProtoDir(dir string, opts WalkOptions, callback func(path string)) error {
+	opts.Match = matchExt(".proto")
+	return Walk(dir, opts, callback)
+}
+
+// WalkLanguages 遍历目录，输出属于任一语言插件的文件及其语言，忽略 opts.Match
+func WalkLanguages(dir string, opts WalkOptions, languages []Language, callback func(path string, lang Language)) error {
+	opts.Match = func(path string) bool {
+		return LanguageFor(path, languages) != nil
+	}
+	return Walk(dir, opts, func(path string) {
+		callback(path, LanguageFor(path, languages))
+	})
+}
+
+func matchExt(ext string) func(path string) bool {
+	return func(path string) bool {
+		return filepath.Ext(path) == ext
+	}
+}
+
+// Walk 按选项遍历目录，只有根目录无法读取时返回错误
+func Walk(dir string, opts WalkOptions, callback func(path string)) error {
+	if opts.Filter == nil {
+		filter, err := NewFileFilter(dir, nil, nil)
 		if err != nil {
-			//fmt.Println("Error:", err)
 			return err
 		}
+		opts.Filter = filter
+	}
+	if opts.Match == nil {
+		opts.Match = matchExt(".go")
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(path string, err error) {
+			fmt.Printf("Walk error: %s: %v\n", path, err)
+		}
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	// 根目录无法读取时直接返回错误
+	root, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	root.Close()
+
+	w := &walker{opts: opts, callback: callback, visited: make(map[string]bool)}
+	w.walkDir(dir)
+	// 所有真实目录遍历完成后再进入符号链接，遍历符号链接时发现的符号链接追加到队列末尾
+	for len(w.links) > 0 {
+		link := w.links[0]
+		w.links = w.links[1:]
+		w.walkLink(link)
+	}
+	return nil
+}
 
-		if strings.HasSuffix(path, "_test.go") {
-			//fmt.Println("Skip:", path)
-			return nil
+type walker struct {
+	opts     WalkOptions
+	callback func(path string)
+	// visited 已遍历目录的真实路径
+	visited map[string]bool
+	// links 等待遍历的指向目录的符号链接
+	links []string
+}
+
+func (w *walker) walkDir(dir string) {
+	filter := w.opts.Filter
+	if skip, reason := filter.Skip(dir, true); skip {
+		filter.logSkip(dir, reason)
+		return
+	}
+
+	real, err := realPath(dir)
+	if err != nil {
+		w.opts.OnError(dir, err)
+		return
+	}
+	if w.visited[real] {
+		filter.logSkip(dir, "directory already visited")
+		return
+	}
+	w.visited[real] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		w.opts.OnError(dir, err)
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			target, err := os.Stat(path)
+			if err != nil {
+				w.opts.OnError(path, err)
+				continue
+			}
+			if target.IsDir() {
+				if w.opts.FollowSymlinks {
+					w.links = append(w.links, path)
+				} else {
+					filter.logSkip(path, "symlink to directory")
+				}
+				continue
+			}
 		}
 
-		if !info.IsDir() && filepath.Ext(path) == ".go" && !isIgnored(path) {
-			fmt.Println(path)
-			callback(path)
+		if isDir {
+			w.walkDir(path)
+		} else {
+			w.walkFile(path)
 		}
-		return nil
-	})
+	}
+}
+
+// walkLink 进入指向目录的符号链接：指向所在目录或上级目录时报告 ErrSymlinkLoop，
+// 指向其他已遍历的目录时跳过，这些目录已经使用自己的路径遍历过
+func (w *walker) walkLink(link string) {
+	target, err := realPath(link)
+	if err != nil {
+		w.opts.OnError(link, err)
+		return
+	}
+	if w.visited[target] {
+		parent, err := realPath(filepath.Dir(link))
+		if err == nil && (parent == target || strings.HasPrefix(parent, target+string(filepath.Separator))) {
+			w.opts.OnError(link, ErrSymlinkLoop)
+		} else {
+			w.opts.Filter.logSkip(link, "symlink to directory already visited")
+		}
+		return
+	}
+	w.walkDir(link)
+}
+
+// realPath 解析符号链接后的绝对路径
+func realPath(path string) (string, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(real)
+}
+
+func (w *walker) walkFile(path string) {
+	filter := w.opts.Filter
+	if skip, reason := filter.Skip(path, false); skip {
+		filter.logSkip(path, reason)
+		return
+	}
+	if !w.opts.Match(path) {
+		return
+	}
+
+	if IsTestFile(path) && !filter.IncludeTests {
+		filter.logSkip(path, "test file")
+		return
+	}
+
+	if filter.SkipGenerated {
+		if generated, err := DetectGeneratedFile(path); err == nil && generated.Generated {
+			filter.logSkip(path, "generated by "+generated.Generator+", "+generated.Reason)
+			return
+		}
+	}
+
+	if filter.Verbose {
+		fmt.Println(path)
+	}
+	w.callback(path)
 }
@@ -2,6 +2,10 @@ package code
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -17,3 +21,139 @@ func TestWalkDir(t *testing.T) {
 
 	})
 }
+
+func TestFileFilter(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":                "*.gen.go\n/build/\n!keep.gen.go\n",
+		".codeanalysisignore":       "docs/\n",
+		".git/info/exclude":         "local.go\n",
+		"main.go":                   "package main",
+		"a.gen.go":                  "package main",
+		"keep.gen.go":               "package main",
+		"local.go":                  "package main",
+		"build/out.go":              "package build",
+		"latest/v.go":               "package latest",
+		"test/e2e.go":               "package test",
+		"docs/doc.go":               "package docs",
+		"internal/x.go":             "package internal",
+		"internal/.gitignore":       "skip.go\n",
+		"internal/skip.go":          "package internal",
+		"internal/sub/skip.go":      "package sub",
+		"internal/sub/main_test.go": "package sub",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	walk := func(includes, excludes []string) []string {
+		filter, err := NewFileFilter(root, includes, excludes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		err = WalkDirFiltered(root, filter, func(path string) {
+			rel, _ := filepath.Rel(root, path)
+			got = append(got, filepath.ToSlash(rel))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	want := []string{"internal/x.go", "keep.gen.go", "latest/v.go", "main.go"}
+	if got := walk(nil, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("walk = %v, want %v", got, want)
+	}
+	want = []string{"internal/x.go"}
+	if got := walk([]string{"internal/**"}, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("walk with include = %v, want %v", got, want)
+	}
+	want = []string{"internal/x.go", "main.go"}
+	if got := walk(nil, []string{"*.gen.go", "latest"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("walk with exclude = %v, want %v", got, want)
+	}
+}
+
+func TestWalkSymlinks(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "src", "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "src", "pkg", "a.go"), []byte("package pkg\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	external := t.TempDir()
+	if err := os.WriteFile(filepath.Join(external, "b.go"), []byte("package ext\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"ext":          external,
+		"src/pkg/loop": "..",
+		"linked":       "src/pkg",
+		"broken.go":    "missing.go",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skip("symlinks not supported:", err)
+		}
+	}
+
+	walk := func(follow bool) ([]string, []string) {
+		var files, errs []string
+		opts := WalkOptions{
+			FollowSymlinks: follow,
+			OnError: func(path string, err error) {
+				rel, _ := filepath.Rel(root, path)
+				errs = append(errs, filepath.ToSlash(rel))
+			},
+		}
+		if err := Walk(root, opts, func(path string) {
+			rel, _ := filepath.Rel(root, path)
+			files = append(files, filepath.ToSlash(rel))
+		}); err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(files)
+		sort.Strings(errs)
+		return files, errs
+	}
+
+	files, errs := walk(false)
+	if want := []string{"src/pkg/a.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+	if want := []string{"broken.go"}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors = %v, want %v", errs, want)
+	}
+
+	// 真实目录先于符号链接遍历并保留自己的路径：linked 指向已经遍历过的 src/pkg 直接跳过，
+	// src/pkg/loop 指向上级目录 src 报告为循环，指向根目录之外的 ext 正常遍历
+	files, errs = walk(true)
+	if want := []string{"ext/b.go", "src/pkg/a.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("follow files = %v, want %v", files, want)
+	}
+	if want := []string{"broken.go", "src/pkg/loop"}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("follow errors = %v, want %v", errs, want)
+	}
+
+	// root 用户不受目录权限限制
+	if os.Geteuid() != 0 {
+		denied := filepath.Join(root, "denied")
+		if err := os.Mkdir(denied, 0000); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chmod(denied, 0755)
+		if _, errs := walk(false); !reflect.DeepEqual(errs, []string{"broken.go", "denied"}) {
+			t.Errorf("permission errors = %v", errs)
+		}
+	}
+}